@@ -0,0 +1,51 @@
+// Command seed loads mock companies and job postings into the database.
+//
+//	go run ./cmd/seed --file mockdata.txt [--reset] [--dry-run]
+//
+// Applying the same file again is a no-op; after the file changes, the rows it describes are updated in place.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"howtv-server/config"
+	"howtv-server/database"
+	"howtv-server/scripts"
+	"howtv-server/services"
+)
+
+func main() {
+	file := flag.String("file", "mockdata.txt", "mock data file to apply")
+	reset := flag.Bool("reset", false, "delete all companies and job postings before seeding")
+	dryRun := flag.Bool("dry-run", false, "report the changes without saving them")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	db, err := database.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	// 求人のスキル抽出に保存済みのスキル分類を使う
+	if err := services.LoadSkillTaxonomy(db); err != nil {
+		log.Fatalf("Failed to load skill taxonomy: %v", err)
+	}
+
+	result, err := scripts.SeedDatabase(db, *file, scripts.SeedOptions{Reset: *reset, DryRun: *dryRun})
+	if err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
+
+	if result.Skipped {
+		log.Printf("%s (sha256 %s) is already applied; pass --reset to apply it again", result.File, result.Checksum[:12])
+		return
+	}
+	prefix := "Seeded"
+	if *dryRun {
+		prefix = "Dry run, nothing saved; would seed"
+	}
+	log.Printf("%s %s: companies %d created / %d updated, jobs %d created / %d updated, positions %d created",
+		prefix, result.File, result.CompaniesCreated, result.CompaniesUpdated,
+		result.JobsCreated, result.JobsUpdated, result.PositionsCreated)
+}
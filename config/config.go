@@ -1,19 +1,92 @@
 package config
 
 import (
+	"encoding/json"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	OpenAIAPIKey string
+	// ロードマップ生成に使うLLM (openai, openai-compatible, stub)
+	LLMProvider string
+	// openai-compatible の場合の接続先 (例: http://localhost:11434/v1)
+	LLMBaseURL string
+	// 使用するモデル名（openaiの場合は省略可）
+	LLMModel string
+	// 質問タイプごとのモデルと生成パラメーター（LLM_CONFIG_FILE と環境変数から読み込む）
+	Generation GenerationConfig
+	// LLM呼び出し1回あたりのタイムアウト（リトライを含む）
+	LLMTimeout time.Duration
+	// 429・5xx・通信エラー時のリトライ回数
+	LLMMaxRetries int
+	// LLMの月間予算（USD、0は無制限）。超えるとキャッシュ済みのロードマップのみ返す
+	LLMMonthlyBudget float64
+	// 設定したモデル（LLM_MODEL と質問タイプごとのモデル）の価格（USD / 100万トークン）。0の場合は既定の価格表を使う
+	LLMInputPrice  float64
+	LLMOutputPrice float64
+	// 非同期のロードマップ生成を同時に処理する数
+	RoadmapWorkers int
+	// 埋め込みのプロンプトテンプレートを上書きするディレクトリ
+	PromptDir string
+	// 募集期限切れの求人をクローズする間隔
+	JobExpiryInterval time.Duration
+	// データベースの種類 (sqlite, postgres, mysql)
+	DBDriver string
+	// 接続文字列。sqliteの場合はファイルのパスで、省略時は DB_PATH を使う
+	DBDSN string
 }
 
+// ModelParams are the model and generation parameters of roadmaps. Unset fields inherit the defaults.
+type ModelParams struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+}
+
+// GenerationConfig is the content of LLM_CONFIG_FILE
+type GenerationConfig struct {
+	Default       ModelParams            `json:"default"`
+	QuestionTypes map[string]ModelParams `json:"question_types"`
+	// リクエストで指定できるモデル（設定済みのモデルは常に指定できる）
+	AllowedModels []string `json:"allowed_models"`
+}
+
+// Models returns the default model and the models of the question types, without duplicates
+func (c GenerationConfig) Models() []string {
+	var models []string
+	seen := map[string]bool{}
+	add := func(model string) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	add(c.Default.Model)
+	qts := make([]string, 0, len(c.QuestionTypes))
+	for qt := range c.QuestionTypes {
+		qts = append(qts, qt)
+	}
+	sort.Strings(qts)
+	for _, qt := range qts {
+		add(c.QuestionTypes[qt].Model)
+	}
+	return models
+}
+
+// 質問タイプごとの環境変数 (LLM_SKILLS_MODEL など) を読み込む対象
+var questionTypes = []string{"general", "skills", "learning", "career"}
+
 var (
 	instance *Config
 	once     sync.Once
@@ -46,6 +119,24 @@ func LoadConfig() *Config {
 		}
 
 		instance.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
+		instance.LLMProvider = os.Getenv("LLM_PROVIDER")
+		instance.LLMBaseURL = os.Getenv("LLM_BASE_URL")
+		instance.LLMModel = os.Getenv("LLM_MODEL")
+		instance.Generation = loadGenerationConfig(os.Getenv("LLM_CONFIG_FILE"))
+		// LLM_MODEL は設定ファイルの既定のモデルより優先する
+		if instance.LLMModel == "" {
+			instance.LLMModel = instance.Generation.Default.Model
+		}
+		instance.Generation.Default.Model = instance.LLMModel
+		instance.LLMTimeout = getEnvDuration("LLM_TIMEOUT", 60*time.Second)
+		instance.LLMMaxRetries = getEnvInt("LLM_MAX_RETRIES", 3)
+		instance.LLMMonthlyBudget = getEnvFloat("LLM_MONTHLY_BUDGET", 0)
+		instance.LLMInputPrice = getEnvFloat("LLM_INPUT_PRICE", 0)
+		instance.LLMOutputPrice = getEnvFloat("LLM_OUTPUT_PRICE", 0)
+		instance.RoadmapWorkers = getEnvInt("ROADMAP_WORKERS", 2)
+		instance.PromptDir = os.Getenv("PROMPT_DIR")
+		instance.JobExpiryInterval = getEnvDuration("JOB_EXPIRY_INTERVAL", time.Hour)
+		instance.DBDriver, instance.DBDSN = loadDatabaseConfig()
 
 		// 設定の検証とログ出力
 		validateAndLogConfig()
@@ -54,6 +145,22 @@ func LoadConfig() *Config {
 	return instance
 }
 
+// loadDatabaseConfig reads DB_DRIVER and DB_DSN. SQLite falls back to the file at DB_PATH.
+func loadDatabaseConfig() (string, string) {
+	driver := strings.ToLower(os.Getenv("DB_DRIVER"))
+	if driver == "" {
+		driver = "sqlite"
+	}
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" && driver == "sqlite" {
+		dsn = os.Getenv("DB_PATH")
+		if dsn == "" {
+			dsn = "test.db"
+		}
+	}
+	return driver, dsn
+}
+
 func getProjectRoot() string {
 	_, filename, _, _ := runtime.Caller(0)
 
@@ -63,12 +170,129 @@ func getProjectRoot() string {
 }
 
 func validateAndLogConfig() {
-	if instance.OpenAIAPIKey == "" {
+	if instance.LLMProvider != "" && instance.LLMProvider != "openai" {
+		log.Printf("LLMプロバイダー: %s", instance.LLMProvider)
+	} else if instance.OpenAIAPIKey == "" {
 		log.Println("警告: OPENAI_API_KEY が設定されていません")
 	} else {
 		maskedKey := maskAPIKey(instance.OpenAIAPIKey)
 		log.Printf("OpenAI APIキーが設定されています: %s", maskedKey)
 	}
+
+	if instance.DBDriver != "sqlite" && instance.DBDSN == "" {
+		log.Printf("警告: DB_DRIVER=%s には DB_DSN が必要です", instance.DBDriver)
+	}
+}
+
+// loadGenerationConfig reads the generation parameters from the JSON file at path and overrides them with
+// LLM_TEMPERATURE, LLM_MAX_TOKENS, LLM_TOP_P, LLM_ALLOWED_MODELS and LLM_<QUESTION_TYPE>_{MODEL,TEMPERATURE,MAX_TOKENS,TOP_P}
+func loadGenerationConfig(path string) GenerationConfig {
+	cfg := GenerationConfig{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			log.Printf("警告: LLM_CONFIG_FILE を読み込めませんでした (%s): %v", path, err)
+			cfg = GenerationConfig{}
+		}
+	}
+
+	applyEnvParams("LLM", &cfg.Default)
+	for _, qt := range questionTypes {
+		params := cfg.QuestionTypes[qt]
+		applyEnvParams("LLM_"+strings.ToUpper(qt), &params)
+		if params != (ModelParams{}) {
+			if cfg.QuestionTypes == nil {
+				cfg.QuestionTypes = map[string]ModelParams{}
+			}
+			cfg.QuestionTypes[qt] = params
+		}
+	}
+	if models := os.Getenv("LLM_ALLOWED_MODELS"); models != "" {
+		cfg.AllowedModels = nil
+		for _, model := range strings.Split(models, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				cfg.AllowedModels = append(cfg.AllowedModels, model)
+			}
+		}
+	}
+	return cfg
+}
+
+// applyEnvParams overrides params with the environment variables starting with prefix.
+// The model of the defaults is read from LLM_MODEL by LoadConfig.
+func applyEnvParams(prefix string, params *ModelParams) {
+	if prefix != "LLM" {
+		if model := os.Getenv(prefix + "_MODEL"); model != "" {
+			params.Model = model
+		}
+	}
+	if t := getEnvFloatPtr(prefix+"_TEMPERATURE", 2); t != nil {
+		params.Temperature = t
+	}
+	params.MaxTokens = getEnvInt(prefix+"_MAX_TOKENS", params.MaxTokens)
+	if p := getEnvFloatPtr(prefix+"_TOP_P", 1); p != nil {
+		params.TopP = p
+	}
+}
+
+// 環境変数を0以上max以下の小数として読み込む（未設定・不正な値の場合はnil）
+func getEnvFloatPtr(key string, max float64) *float32 {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 32)
+	if err != nil || f < 0 || f > max {
+		log.Printf("警告: %s の値が不正です (%q)。0以上%g以下で指定してください", key, value, max)
+		return nil
+	}
+	f32 := float32(f)
+	return &f32
+}
+
+// 環境変数を時間として読み込む（未設定・不正な値の場合はデフォルト値）
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("警告: %s の値が不正です (%q)。デフォルト値 %s を使用します", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// 環境変数を0以上の整数として読み込む（未設定・不正な値の場合はデフォルト値）
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("警告: %s の値が不正です (%q)。デフォルト値 %d を使用します", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// 環境変数を0以上の小数として読み込む（未設定・不正な値の場合はデフォルト値）
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		log.Printf("警告: %s の値が不正です (%q)。デフォルト値 %g を使用します", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
 }
 
 // APIキーの一部をマスクして表示
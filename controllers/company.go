@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"howtv-server/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// companyInput is the request body accepted by CreateCompany and UpdateCompany
+type companyInput struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	Address  string `json:"address" binding:"max=255"`
+	Industry string `json:"industry" binding:"max=100"`
+	Website  string `json:"website" binding:"omitempty,url"`
+	LogoURL  string `json:"logo_url" binding:"omitempty,url"`
+}
+
+// parseCompanyID reads the :id path parameter and writes a 400 response if it is invalid
+func parseCompanyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// findCompany loads a company by its ID and writes a 404 response if it does not exist
+func findCompany(c *gin.Context, id uint) (models.Company, bool) {
+	var company models.Company
+	if err := DB.First(&company, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return company, false
+	}
+	return company, true
+}
+
+// companyNameTaken reports whether another company already uses the given name
+func companyNameTaken(name string, excludeID uint) (bool, error) {
+	var count int64
+	query := DB.Model(&models.Company{}).Where("name = ?", name)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetCompanies returns all companies
+func GetCompanies(c *gin.Context) {
+	var companies []models.Company
+	if err := DB.Order("id").Find(&companies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, companies)
+}
+
+// GetCompany returns a single company
+func GetCompany(c *gin.Context) {
+	id, ok := parseCompanyID(c)
+	if !ok {
+		return
+	}
+
+	company, ok := findCompany(c, id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+// CreateCompany creates a new company
+func CreateCompany(c *gin.Context) {
+	var input companyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 会社名の重複を防ぐ（シードデータも会社名で同一性を判定している）
+	taken, err := companyNameTaken(input.Name, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Company with this name already exists"})
+		return
+	}
+
+	company := models.Company{
+		Name:     input.Name,
+		Address:  input.Address,
+		Industry: input.Industry,
+		Website:  input.Website,
+		LogoURL:  input.LogoURL,
+	}
+
+	if err := DB.Create(&company).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, company)
+}
+
+// UpdateCompany replaces the editable fields of a company
+func UpdateCompany(c *gin.Context) {
+	id, ok := parseCompanyID(c)
+	if !ok {
+		return
+	}
+
+	var input companyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, ok := findCompany(c, id)
+	if !ok {
+		return
+	}
+
+	taken, err := companyNameTaken(input.Name, company.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Company with this name already exists"})
+		return
+	}
+
+	// Select で空文字列への更新も反映させる
+	updates := models.Company{
+		Name:     input.Name,
+		Address:  input.Address,
+		Industry: input.Industry,
+		Website:  input.Website,
+		LogoURL:  input.LogoURL,
+	}
+	if err := DB.Model(&company).Select("Name", "Address", "Industry", "Website", "LogoURL").Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updated models.Company
+	if err := DB.First(&updated, company.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Company updated but failed to retrieve it"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCompany deletes a company.
+// Companies that still have job postings are only deleted when cascade=true is given,
+// in which case their job postings are deleted as well.
+func DeleteCompany(c *gin.Context) {
+	id, ok := parseCompanyID(c)
+	if !ok {
+		return
+	}
+
+	company, ok := findCompany(c, id)
+	if !ok {
+		return
+	}
+
+	var jobCount int64
+	if err := DB.Model(&models.JobPosting{}).Where("company_id = ?", company.ID).Count(&jobCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cascade := c.Query("cascade") == "true"
+	if jobCount > 0 && !cascade {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Company has job postings; delete them first or pass cascade=true",
+			"job_count": jobCount,
+		})
+		return
+	}
+
+	// Start a transaction
+	tx := DB.Begin()
+
+	if jobCount > 0 {
+		var jobs []models.JobPosting
+		if err := tx.Where("company_id = ?", company.ID).Find(&jobs).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range jobs {
+			if err := tx.Model(&jobs[i]).Association("Positions").Clear(); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear positions"})
+				return
+			}
+		}
+
+		if err := tx.Where("company_id = ?", company.ID).Delete(&models.JobPosting{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Delete(&company).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Company deleted successfully",
+		"deleted_jobs": jobCount,
+	})
+}
+
+// GetCompanyJobs returns a page of the job postings of a company
+func GetCompanyJobs(c *gin.Context) {
+	id, ok := parseCompanyID(c)
+	if !ok {
+		return
+	}
+
+	company, ok := findCompany(c, id)
+	if !ok {
+		return
+	}
+
+	respondJobPage(c, DB.Model(&models.JobPosting{}).Where("company_id = ?", company.ID))
+}
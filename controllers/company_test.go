@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// registerCompanyRoutes は会社APIのハンドラーをルーターに登録します
+func registerCompanyRoutes(r *gin.Engine) {
+	r.GET("/api/v1/companies", GetCompanies)
+	r.GET("/api/v1/companies/:id", GetCompany)
+	r.POST("/api/v1/companies", CreateCompany)
+	r.PUT("/api/v1/companies/:id", UpdateCompany)
+	r.DELETE("/api/v1/companies/:id", DeleteCompany)
+	r.GET("/api/v1/companies/:id/jobs", GetCompanyJobs)
+}
+
+// performJSONRequest はJSONボディ付きのリクエストを実行します
+func performJSONRequest(r *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestCreateAndGetCompany は会社の作成と取得をテストします
+func TestCreateAndGetCompany(t *testing.T) {
+	r, _ := setupTestRouter()
+	registerCompanyRoutes(r)
+
+	w := performJSONRequest(r, "POST", "/api/v1/companies", gin.H{
+		"name":     "テスト株式会社",
+		"address":  "東京都渋谷区",
+		"industry": "IT",
+		"website":  "https://test.co.jp",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Company
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("レスポンスのパースに失敗しました: %v", err)
+	}
+	assert.NotZero(t, created.ID)
+
+	w = performJSONRequest(r, "GET", fmt.Sprintf("/api/v1/companies/%d", created.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "テスト株式会社")
+
+	// 同名の会社は作成できない
+	w = performJSONRequest(r, "POST", "/api/v1/companies", gin.H{"name": "テスト株式会社"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestCreateCompanyValidation は入力検証をテストします
+func TestCreateCompanyValidation(t *testing.T) {
+	r, _ := setupTestRouter()
+	registerCompanyRoutes(r)
+
+	// 会社名は必須
+	w := performJSONRequest(r, "POST", "/api/v1/companies", gin.H{"address": "東京都"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// WebサイトはURL形式
+	w = performJSONRequest(r, "POST", "/api/v1/companies", gin.H{"name": "A社", "website": "not a url"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestUpdateCompany は会社情報の更新をテストします
+func TestUpdateCompany(t *testing.T) {
+	r, db := setupTestRouter()
+	registerCompanyRoutes(r)
+
+	company := models.Company{Name: "旧社名", Industry: "IT"}
+	db.Create(&company)
+
+	w := performJSONRequest(r, "PUT", fmt.Sprintf("/api/v1/companies/%d", company.ID), gin.H{"name": "新社名"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.Company
+	db.First(&updated, company.ID)
+	assert.Equal(t, "新社名", updated.Name)
+	assert.Equal(t, "", updated.Industry)
+
+	// 存在しない会社は404
+	w = performJSONRequest(r, "PUT", "/api/v1/companies/9999", gin.H{"name": "新社名"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestGetCompanyNotFound は存在しない会社・不正なIDの扱いをテストします
+func TestGetCompanyNotFound(t *testing.T) {
+	r, _ := setupTestRouter()
+	registerCompanyRoutes(r)
+
+	w := performJSONRequest(r, "GET", "/api/v1/companies/9999", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performJSONRequest(r, "GET", "/api/v1/companies/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestDeleteCompanyWithJobs は求人を持つ会社の削除をテストします
+func TestDeleteCompanyWithJobs(t *testing.T) {
+	r, db := setupTestRouter()
+	registerCompanyRoutes(r)
+
+	company := models.Company{Name: "削除テスト株式会社"}
+	db.Create(&company)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	db.Model(&job).Update("company_id", company.ID)
+
+	// 会社の求人一覧
+	w := performJSONRequest(r, "GET", fmt.Sprintf("/api/v1/companies/%d/jobs", company.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var jobs struct {
+		Data []models.JobPosting `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &jobs)
+	assert.Equal(t, 1, len(jobs.Data))
+	assert.Equal(t, 1, len(jobs.Data[0].Positions))
+
+	// cascade なしでは削除できない
+	w = performJSONRequest(r, "DELETE", fmt.Sprintf("/api/v1/companies/%d", company.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// cascade=true で求人ごと削除
+	w = performJSONRequest(r, "DELETE", fmt.Sprintf("/api/v1/companies/%d?cascade=true", company.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var jobCount int64
+	db.Model(&models.JobPosting{}).Where("company_id = ?", company.ID).Count(&jobCount)
+	assert.Equal(t, int64(0), jobCount)
+
+	w = performJSONRequest(r, "GET", fmt.Sprintf("/api/v1/companies/%d", company.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
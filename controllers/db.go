@@ -2,8 +2,16 @@ package controllers
 
 import (
 	"gorm.io/gorm"
+
+	"howtv-server/services"
 )
 
 var (
 	DB *gorm.DB
+	// Roadmaps はロードマップの生成に使うサービス（main.go やテストで設定する）
+	Roadmaps services.RoadmapGenerator
+	// RoadmapTasks は非同期のロードマップ生成を処理するワーカープール
+	RoadmapTasks *services.RoadmapWorkerPool
+	// Usage はLLMの利用量の記録と月間予算の管理（予算を設定しない場合も記録する）
+	Usage *services.UsageTracker
 )
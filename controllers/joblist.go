@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"howtv-server/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// jobSorts lists the columns the job list can be sorted by
+var jobSorts = map[string]sortSpec{
+	"created_at": {
+		Column:      "created_at",
+		DefaultDesc: true,
+		Parse: func(v string) (interface{}, error) {
+			return time.Parse(time.RFC3339Nano, v)
+		},
+	},
+	"title": {Column: "title"},
+	"salary": {
+		Column:      "salary_min",
+		DefaultDesc: true,
+		Parse: func(v string) (interface{}, error) {
+			return strconv.ParseInt(v, 10, 64)
+		},
+	},
+}
+
+// jobSortValue returns the cursor value of a job for the given sort key
+func jobSortValue(job models.JobPosting, sort string) string {
+	switch sort {
+	case "title":
+		return job.Title
+	case "salary":
+		return strconv.FormatInt(job.SalaryMin, 10)
+	default:
+		return job.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// queryValues returns all values of a query parameter.
+// Both repeated parameters (?status=a&status=b) and comma separated values (?status=a,b) are accepted.
+func queryValues(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// queryIDs returns the values of a query parameter parsed as IDs
+func queryIDs(c *gin.Context, key string) ([]uint, error) {
+	var ids []uint
+	for _, v := range queryValues(c, key) {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("%s must be a list of positive integers", key)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// resolveSkillNames returns the IDs of the skills with the given names or aliases
+func resolveSkillNames(names []string) ([]uint, error) {
+	var skills []models.Skill
+	if err := DB.Preload("Aliases").Find(&skills).Error; err != nil {
+		return nil, err
+	}
+	byTerm := map[string]uint{}
+	for i := range skills {
+		for _, term := range skills[i].Terms() {
+			byTerm[term] = skills[i].ID
+		}
+	}
+
+	ids := make([]uint, 0, len(names))
+	for _, name := range names {
+		id, ok := byTerm[models.NormalizeSkillTerm(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown skill: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// resolvePositionNames returns the IDs of the positions with the given names, display names or aliases
+func resolvePositionNames(names []string) ([]uint, error) {
+	byTerm, err := models.PositionsByTerm(DB)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(names))
+	for _, name := range names {
+		position, ok := byTerm[models.NormalizeSkillTerm(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown position: %s", name)
+		}
+		ids = append(ids, position.ID)
+	}
+	return ids, nil
+}
+
+// likePattern builds a case-insensitive LIKE pattern; use it with ESCAPE '!'
+func likePattern(s string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return "%" + strings.ToLower(r.Replace(s)) + "%"
+}
+
+// applyJobFilters adds the structured filters given in the query string to a job posting query.
+// Multiple values of one parameter are ORed, different parameters are ANDed.
+func applyJobFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if locations := queryValues(c, "location"); len(locations) > 0 {
+		conds := make([]string, len(locations))
+		args := make([]interface{}, len(locations))
+		for i, loc := range locations {
+			conds[i] = "LOWER(job_postings.location) LIKE ? ESCAPE '!'"
+			args[i] = likePattern(loc)
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+
+	if types := queryValues(c, "employment_type"); len(types) > 0 {
+		query = query.Where("job_postings.employment_type IN ?", types)
+	}
+
+	if values := queryValues(c, "status"); len(values) > 0 {
+		statuses := make([]models.JobStatus, len(values))
+		for i, v := range values {
+			status, ok := models.ParseJobStatus(v)
+			if !ok {
+				return nil, fmt.Errorf("unknown status: %s", v)
+			}
+			statuses[i] = status
+		}
+		query = query.Where("job_postings.status IN ?", statuses)
+	}
+
+	positionIDs, err := queryIDs(c, "position_id")
+	if err != nil {
+		return nil, err
+	}
+	// position=Backend Engineer は名前・表示名・別名で指定する。どちらもカテゴリの配下のポジションを含む
+	if names := queryValues(c, "position"); len(names) > 0 {
+		ids, err := resolvePositionNames(names)
+		if err != nil {
+			return nil, err
+		}
+		positionIDs = append(positionIDs, ids...)
+	}
+	if len(positionIDs) > 0 {
+		jobs, err := positionSubtreeJobs(positionIDs)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("job_postings.id IN (?)", jobs)
+	}
+
+	// skill=go&skill=react はいずれかのスキルを求める求人に絞り込む（名前と別名のどちらでもよい）
+	if names := queryValues(c, "skill"); len(names) > 0 {
+		skillIDs, err := resolveSkillNames(names)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("job_postings.id IN (?)",
+			DB.Table("job_skills").Select("job_posting_id").Where("skill_id IN ?", skillIDs))
+	}
+
+	companyIDs, err := queryIDs(c, "company_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(companyIDs) > 0 {
+		query = query.Where("job_postings.company_id IN ?", companyIDs)
+	}
+
+	now := time.Now().UTC()
+
+	// open_only=true は公開中で募集期限内の求人に絞り込む
+	if c.Query("open_only") == "true" {
+		query = query.Where("(job_postings.closing_date IS NULL OR job_postings.closing_date >= ?) AND job_postings.status = ?",
+			now, models.JobStatusPublished)
+	}
+
+	// closing_within=7d は指定期間内に募集が締め切られる求人に絞り込む
+	if v := c.Query("closing_within"); v != "" {
+		d, err := parseDayDuration(v)
+		if err != nil {
+			return nil, errors.New("closing_within must be a duration such as 7d, 2w or 48h")
+		}
+		query = query.Where("job_postings.closing_date BETWEEN ? AND ?", now, now.Add(d))
+	}
+
+	periods := queryValues(c, "salary_period")
+	if len(periods) > 0 {
+		query = query.Where("job_postings.period IN ?", periods)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	if currency != "" {
+		query = query.Where("job_postings.currency = ?", currency)
+	}
+
+	// 並び替えの指定は respondJobPage で確認する
+	if c.Query("salary_min") != "" || c.Query("salary_max") != "" {
+		if _, _, err := salaryScope(c); err != nil {
+			return nil, err
+		}
+	}
+
+	// salary_min は上限（上限がなければ下限）が指定額以上の求人、
+	// salary_max は下限（下限がなければ上限）が指定額以下の求人に絞り込む。給与が不明な求人は除外される
+	if v := c.Query("salary_min"); v != "" {
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || amount < 0 {
+			return nil, errors.New("salary_min must be a non-negative integer")
+		}
+		query = query.Where("COALESCE(NULLIF(job_postings.salary_max, 0), job_postings.salary_min) >= ?", amount)
+	}
+	if v := c.Query("salary_max"); v != "" {
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || amount < 0 {
+			return nil, errors.New("salary_max must be a non-negative integer")
+		}
+		query = query.Where("COALESCE(NULLIF(job_postings.salary_min, 0), job_postings.salary_max) BETWEEN 1 AND ?", amount)
+	}
+
+	return query, nil
+}
+
+// parseDayDuration parses durations in days ("7d") or weeks ("2w") as well as Go durations ("48h")
+func parseDayDuration(v string) (time.Duration, error) {
+	if n := len(v); n > 1 && (v[n-1] == 'd' || v[n-1] == 'w') {
+		count, err := strconv.Atoi(v[:n-1])
+		if err != nil || count <= 0 {
+			return 0, errors.New("invalid duration")
+		}
+		days := count
+		if v[n-1] == 'w' {
+			days *= 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return d, nil
+}
+
+// searchTerms splits the q parameter into whitespace separated terms
+func searchTerms(c *gin.Context) ([]string, error) {
+	var terms []string
+	for _, q := range queryValues(c, "q") {
+		for _, term := range strings.Fields(q) {
+			if len([]rune(term)) > 100 {
+				return nil, errors.New("q terms must be at most 100 characters")
+			}
+			terms = append(terms, term)
+		}
+	}
+	return terms, nil
+}
+
+// applyTextFilter requires every term to appear in the title, description or requirements
+func applyTextFilter(query *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		pattern := likePattern(term)
+		query = query.Where(
+			"(LOWER(job_postings.title) LIKE ? ESCAPE '!' OR LOWER(job_postings.description) LIKE ? ESCAPE '!' OR LOWER(job_postings.requirements) LIKE ? ESCAPE '!')",
+			pattern, pattern, pattern,
+		)
+	}
+	return query
+}
+
+// errSalaryScope is returned when salary amounts would be compared across currencies or periods
+var errSalaryScope = errors.New("salary_min, salary_max and sort=salary require currency and a single salary_period")
+
+// salaryScope returns the currency and salary_period that salary amounts are compared within.
+// Amounts in different currencies or periods cannot be compared, so exactly one of each is required.
+func salaryScope(c *gin.Context) (string, string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	periods := queryValues(c, "salary_period")
+	if currency == "" || len(periods) != 1 {
+		return "", "", errSalaryScope
+	}
+	return currency, periods[0], nil
+}
+
+// respondJobPage writes one page of the job postings matched by query in the list envelope.
+// It handles the limit/page/cursor/sort/order, include_company and fields query parameters.
+func respondJobPage(c *gin.Context, query *gorm.DB) {
+	params, err := parsePageParams(c, jobSorts, "created_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 給与での並び替えは一覧の種類に関わらず、1つの通貨と期間の中でのみ行う
+	if params.Sort == "salary" {
+		currency, period, err := salaryScope(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("job_postings.currency = ? AND job_postings.period = ?", currency, period)
+	}
+
+	fields, err := parseJobFields(c.Query("fields"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pageQuery, err := params.apply(query.Preload("Positions").Preload("Skills"), "job_postings")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// include_company=true の場合は会社情報も含める
+	if c.Query("include_company") == "true" {
+		pageQuery = pageQuery.Preload("Company")
+	}
+
+	var jobs []models.JobPosting
+	if err := pageQuery.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hasMore := len(jobs) > params.Limit
+	if hasMore {
+		jobs = jobs[:params.Limit]
+	}
+
+	var nextCursor *string
+	if hasMore {
+		last := jobs[len(jobs)-1]
+		cursor := encodeCursor(pageCursor{Value: jobSortValue(last, params.Sort), ID: last.ID})
+		nextCursor = &cursor
+	}
+
+	response := pageResponse{
+		Data:       jobs,
+		Total:      total,
+		Limit:      params.Limit,
+		Page:       params.Page,
+		NextCursor: nextCursor,
+		Links:      params.links(c, nextCursor, hasMore),
+	}
+
+	if len(fields) > 0 {
+		selected, err := selectJobFields(jobs, fields)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.Data = selected
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// jobFieldNames returns the JSON keys of a serialized job posting
+func jobFieldNames() map[string]bool {
+	data, _ := json.Marshal(models.JobPosting{})
+	var keys map[string]json.RawMessage
+	json.Unmarshal(data, &keys)
+
+	names := make(map[string]bool, len(keys))
+	for k := range keys {
+		names[k] = true
+	}
+	return names
+}
+
+// parseJobFields parses the comma separated fields parameter (e.g. fields=title,location)
+func parseJobFields(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	allowed := jobFieldNames()
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !allowed[f] {
+			return nil, fmt.Errorf("unknown field: %s", f)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// selectJobFields reduces each job to the requested JSON keys. uuid is always kept.
+func selectJobFields(jobs []models.JobPosting, fields []string) ([]map[string]json.RawMessage, error) {
+	result := make([]map[string]json.RawMessage, 0, len(jobs))
+	for _, job := range jobs {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		selected := map[string]json.RawMessage{"uuid": all["uuid"]}
+		for _, f := range fields {
+			selected[f] = all[f]
+		}
+		result = append(result, selected)
+	}
+	return result, nil
+}
@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// jobListResponse は求人一覧APIのレスポンス形式です
+type jobListResponse struct {
+	Data       []models.JobPosting `json:"data"`
+	Total      int64               `json:"total"`
+	Limit      int                 `json:"limit"`
+	Page       int                 `json:"page"`
+	NextCursor *string             `json:"next_cursor"`
+	Links      struct {
+		Self string `json:"self"`
+		Next string `json:"next"`
+		Prev string `json:"prev"`
+	} `json:"links"`
+}
+
+// createNumberedJobs はタイトルに連番を付けた求人を作成します
+func createNumberedJobs(t *testing.T, db *gorm.DB, n int) {
+	for i := 1; i <= n; i++ {
+		job := models.JobPosting{Title: fmt.Sprintf("求人%02d", i), Location: "東京"}
+		if err := db.Create(&job).Error; err != nil {
+			t.Fatalf("テストデータの作成に失敗しました: %v", err)
+		}
+	}
+}
+
+// getJobList は求人一覧APIを呼び出してレスポンスをパースします
+func getJobList(t *testing.T, r *gin.Engine, path string) jobListResponse {
+	w := performJSONRequest(r, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期待されるステータス200に対して%dが返されました: %s", w.Code, w.Body.String())
+	}
+	var response jobListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("レスポンスのパースに失敗しました: %v", err)
+	}
+	return response
+}
+
+// TestJobListOffsetPagination はpage/limitによるページングをテストします
+func TestJobListOffsetPagination(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+	createNumberedJobs(t, db, 5)
+
+	first := getJobList(t, r, "/api/v1/jobs?limit=2&sort=title")
+	assert.Equal(t, int64(5), first.Total)
+	assert.Equal(t, 2, len(first.Data))
+	assert.Equal(t, "求人01", first.Data[0].Title)
+	assert.NotEmpty(t, first.Links.Next)
+	assert.Empty(t, first.Links.Prev)
+
+	third := getJobList(t, r, "/api/v1/jobs?limit=2&sort=title&page=3")
+	assert.Equal(t, 1, len(third.Data))
+	assert.Equal(t, "求人05", third.Data[0].Title)
+	assert.Nil(t, third.NextCursor)
+	assert.Empty(t, third.Links.Next)
+	assert.NotEmpty(t, third.Links.Prev)
+}
+
+// TestJobListCursorPagination はカーソルによるページングで全件を重複なく取得できることをテストします
+func TestJobListCursorPagination(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+	createNumberedJobs(t, db, 5)
+
+	base := "/api/v1/jobs?limit=2&sort=title&order=desc"
+	var titles []string
+	path := base
+	for i := 0; i < 5; i++ {
+		page := getJobList(t, r, path)
+		for _, job := range page.Data {
+			titles = append(titles, job.Title)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		path = base + "&cursor=" + *page.NextCursor
+		if i > 0 {
+			assert.Contains(t, page.Links.Next, "cursor=")
+		}
+	}
+
+	assert.Equal(t, []string{"求人05", "求人04", "求人03", "求人02", "求人01"}, titles)
+
+	// デフォルトの created_at 降順でもカーソルで全件を辿れる
+	seen := make(map[string]bool)
+	path = "/api/v1/jobs?limit=2"
+	for i := 0; i < 5; i++ {
+		page := getJobList(t, r, path)
+		for _, job := range page.Data {
+			seen[job.Title] = true
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		path = "/api/v1/jobs?limit=2&cursor=" + *page.NextCursor
+	}
+	assert.Equal(t, 5, len(seen))
+}
+
+// TestJobListFields はfieldsパラメータによるフィールド選択をテストします
+func TestJobListFields(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+	createNumberedJobs(t, db, 1)
+
+	w := performJSONRequest(r, "GET", "/api/v1/jobs?fields=title,location", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, len(response.Data))
+	assert.Equal(t, "求人01", response.Data[0]["title"])
+	assert.Contains(t, response.Data[0], "uuid")
+	assert.NotContains(t, response.Data[0], "description")
+
+	w = performJSONRequest(r, "GET", "/api/v1/jobs?fields=unknown", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestJobListInvalidParams は不正なページングパラメータをテストします
+func TestJobListInvalidParams(t *testing.T) {
+	r, _ := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+
+	for _, path := range []string{
+		"/api/v1/jobs?limit=0",
+		"/api/v1/jobs?page=-1",
+		"/api/v1/jobs?sort=unknown",
+		"/api/v1/jobs?order=up",
+		"/api/v1/jobs?cursor=broken",
+		"/api/v1/jobs?cursor=abc&page=2",
+	} {
+		w := performJSONRequest(r, "GET", path, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+// createFilterTestData はフィルタテスト用の求人を作成します
+func createFilterTestData(t *testing.T, db *gorm.DB) (models.Position, models.Position, models.Company) {
+	frontend := models.Position{Name: "フロントエンドエンジニア"}
+	backend := models.Position{Name: "バックエンドエンジニア"}
+	db.Create(&frontend)
+	db.Create(&backend)
+
+	company := models.Company{Name: "フィルタ株式会社"}
+	db.Create(&company)
+
+	jobs := []models.JobPosting{
+		{Title: "React Engineer", Description: "Build UI with React", Location: "Tokyo (Hybrid)", EmploymentType: "Full-time", Status: "Active", CompanyID: company.ID, Positions: []models.Position{frontend}},
+		{Title: "Go Engineer", Description: "Build APIs in Go", Requirements: "PostgreSQL", Location: "Osaka", EmploymentType: "Full-time", Status: "Active", Positions: []models.Position{backend}},
+		{Title: "Fullstack Engineer", Description: "React and Go", Location: "フルリモート", EmploymentType: "Contract", Status: "Closed", CompanyID: company.ID, Positions: []models.Position{frontend, backend}},
+		{Title: "100% Remote QA", Description: "Testing", Location: "Remote", EmploymentType: "Part-time", Status: "Active"},
+	}
+	for i := range jobs {
+		if err := db.Create(&jobs[i]).Error; err != nil {
+			t.Fatalf("テストデータの作成に失敗しました: %v", err)
+		}
+	}
+
+	return frontend, backend, company
+}
+
+// jobTitles はレスポンスに含まれる求人タイトルを順に返します
+func jobTitles(response jobListResponse) []string {
+	titles := make([]string, 0, len(response.Data))
+	for _, job := range response.Data {
+		titles = append(titles, job.Title)
+	}
+	return titles
+}
+
+// TestJobListFilters は各フィルタと組み合わせをテストします
+func TestJobListFilters(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+	frontend, backend, company := createFilterTestData(t, db)
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"勤務地（部分一致・大文字小文字無視）", "location=tokyo", []string{"React Engineer"}},
+		{"勤務地の複数指定", "location=tokyo&location=osaka", []string{"Go Engineer", "React Engineer"}},
+		{"雇用形態", "employment_type=Full-time", []string{"Go Engineer", "React Engineer"}},
+		{"雇用形態のカンマ区切り", "employment_type=Contract,Part-time", []string{"100% Remote QA", "Fullstack Engineer"}},
+		{"ステータス", "status=Closed", []string{"Fullstack Engineer"}},
+		{"ポジション", fmt.Sprintf("position_id=%d", frontend.ID), []string{"Fullstack Engineer", "React Engineer"}},
+		{"ポジションの複数指定", fmt.Sprintf("position_id=%d&position_id=%d", frontend.ID, backend.ID), []string{"Fullstack Engineer", "Go Engineer", "React Engineer"}},
+		{"会社", fmt.Sprintf("company_id=%d", company.ID), []string{"Fullstack Engineer", "React Engineer"}},
+		{"フリーテキスト", "q=react", []string{"Fullstack Engineer", "React Engineer"}},
+		{"フリーテキストの全語一致", "q=react+go", []string{"Fullstack Engineer"}},
+		{"要件も検索対象", "q=postgresql", []string{"Go Engineer"}},
+		{"ワイルドカードのエスケープ", "q=100%25", []string{"100% Remote QA"}},
+		{"組み合わせ", fmt.Sprintf("position_id=%d&status=Active&employment_type=Full-time", backend.ID), []string{"Go Engineer"}},
+		{"該当なし", "location=tokyo&status=Closed", []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := getJobList(t, r, "/api/v1/jobs?sort=title&"+tc.query)
+			assert.Equal(t, tc.expected, jobTitles(response))
+			assert.Equal(t, int64(len(tc.expected)), response.Total)
+		})
+	}
+
+	w := performJSONRequest(r, "GET", "/api/v1/jobs?position_id=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestJobListSalaryFilters は構造化された給与による絞り込みと並び替えをテストします
+func TestJobListSalaryFilters(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+
+	jobs := []models.JobPosting{
+		{Title: "A", SalaryRange: "¥4M - ¥6M annually"},
+		{Title: "B", SalaryRange: "600万〜800万円"},
+		{Title: "C", SalaryRange: "年収1000万円以上"},
+		{Title: "D", SalaryRange: "時給2,000円"},
+		{Title: "E", SalaryRange: "応相談"},
+		{Title: "F", SalaryRange: "$120k - $150k per year"},
+	}
+	for i := range jobs {
+		db.Create(&jobs[i])
+	}
+
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"currency=JPY&salary_period=year&salary_min=7000000", []string{"B", "C"}},
+		{"currency=JPY&salary_period=year&salary_max=6500000", []string{"A", "B"}},
+		{"currency=JPY&salary_period=hour&salary_max=6500000", []string{"D"}},
+		{"currency=JPY&salary_period=year&salary_min=5000000&salary_max=9000000", []string{"A", "B"}},
+		{"currency=usd&salary_period=year&salary_min=100000", []string{"F"}},
+		{"salary_period=hour", []string{"D"}},
+		{"currency=USD", []string{"F"}},
+		{"currency=JPY&salary_period=year&sort=salary&order=desc", []string{"C", "B", "A"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			query := tc.query
+			if !strings.Contains(query, "sort=") {
+				query += "&sort=title"
+			}
+			response := getJobList(t, r, "/api/v1/jobs?"+query)
+			assert.Equal(t, tc.expected, jobTitles(response))
+		})
+	}
+
+	// 通貨と期間を指定しない金額の比較は400
+	for _, query := range []string{
+		"currency=JPY&salary_period=year&salary_min=abc",
+		"salary_min=100000",
+		"currency=JPY&salary_max=100000",
+		"currency=JPY&salary_period=year,month&salary_min=100000",
+		"salary_period=year&sort=salary",
+	} {
+		w := performJSONRequest(r, "GET", "/api/v1/jobs?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// 会社の求人一覧など、フィルタを受け付けない一覧でも給与の並び替えには通貨と期間が必要
+	r.GET("/api/v1/companies/:id/jobs", GetCompanyJobs)
+	company := models.Company{Name: "給与テスト株式会社"}
+	db.Create(&company)
+	db.Model(&models.JobPosting{}).Where("1 = 1").Update("company_id", company.ID)
+	path := fmt.Sprintf("/api/v1/companies/%d/jobs?sort=salary&order=desc", company.ID)
+	w := performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response := getJobList(t, r, path+"&currency=JPY&salary_period=year")
+	assert.Equal(t, []string{"C", "B", "A"}, jobTitles(response))
+}
+
+// TestJobListDateFilters は募集期限による絞り込みをテストします
+func TestJobListDateFilters(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs", GetJobPostings)
+
+	days := func(n int) *time.Time {
+		d := time.Now().Add(time.Duration(n) * 24 * time.Hour)
+		return &d
+	}
+	jobs := []models.JobPosting{
+		{Title: "A", Status: "Active", ClosingDate: days(3)},
+		{Title: "B", Status: "Active", ClosingDate: days(10)},
+		{Title: "C", Status: "Active", ClosingDate: days(-1)},
+		{Title: "D", Status: "Active"},
+		{Title: "E", Status: models.JobStatusClosed, ClosingDate: days(5)},
+	}
+	for i := range jobs {
+		db.Create(&jobs[i])
+	}
+
+	assert.Equal(t, []string{"A", "B", "D"}, jobTitles(getJobList(t, r, "/api/v1/jobs?sort=title&open_only=true")))
+	assert.Equal(t, []string{"A", "E"}, jobTitles(getJobList(t, r, "/api/v1/jobs?sort=title&closing_within=7d")))
+	assert.Equal(t, []string{"A"}, jobTitles(getJobList(t, r, "/api/v1/jobs?sort=title&closing_within=1w&open_only=true")))
+	assert.Equal(t, []string{"A", "B", "E"}, jobTitles(getJobList(t, r, "/api/v1/jobs?sort=title&closing_within=240h")))
+
+	w := performJSONRequest(r, "GET", "/api/v1/jobs?closing_within=soon", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,37 +1,36 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"howtv-server/models"
+	"howtv-server/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// GetJobPostings returns all job postings with their positions
+// GetJobPostings returns a page of job postings with their positions and skills.
+// Supports the location, employment_type, status, position_id, company_id, skill and q filters.
 func GetJobPostings(c *gin.Context) {
-	var jobs []models.JobPosting
-
-	// クエリパラメータを取得
-	includeCompany := c.Query("include_company") == "true"
-
-	query := DB.Preload("Positions")
-
-	// include_company=true の場合は会社情報も含める
-	if includeCompany {
-		query = query.Preload("Company")
+	query, err := applyJobFilters(c, DB.Model(&models.JobPosting{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := query.Find(&jobs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	terms, err := searchTerms(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, jobs)
+	respondJobPage(c, applyTextFilter(query, terms))
 }
 
-// GetJobPosting returns a single job posting with its positions
+// GetJobPosting returns a single job posting with its positions and skills
 func GetJobPosting(c *gin.Context) {
 	id := c.Param("uuid")
 
@@ -46,7 +45,7 @@ func GetJobPosting(c *gin.Context) {
 	includeCompany := c.Query("include_company") == "true"
 
 	var job models.JobPosting
-	query := DB.Preload("Positions")
+	query := DB.Preload("Positions").Preload("Skills")
 
 	// include_company=true の場合は会社情報も含める
 	if includeCompany {
@@ -61,11 +60,13 @@ func GetJobPosting(c *gin.Context) {
 	c.JSON(http.StatusOK, job)
 }
 
-// CreateJobPosting creates a new job posting
+// CreateJobPosting creates a new job posting.
+// Its skills are extracted from the text unless skill_ids is given, which sets them by hand.
 func CreateJobPosting(c *gin.Context) {
 	var jobDTO struct {
 		models.JobPosting
 		PositionIDs []uint `json:"position_ids"`
+		SkillIDs    []uint `json:"skill_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&jobDTO); err != nil {
@@ -78,11 +79,23 @@ func CreateJobPosting(c *gin.Context) {
 		jobDTO.JobPosting.UUID = uuid.New()
 	}
 
+	// ステータス未指定の場合は下書きとして作成する
+	status, ok := models.ParseJobStatus(string(jobDTO.JobPosting.Status))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: " + string(jobDTO.JobPosting.Status)})
+		return
+	}
+	jobDTO.JobPosting.Status = status
+
+	// スキルは skill_ids か自動抽出でのみ設定する
+	jobDTO.JobPosting.Skills = nil
+	jobDTO.JobPosting.SkillsLocked = false
+
 	// Start a transaction
 	tx := DB.Begin()
 
-	// Create the job posting
-	if err := tx.Create(&jobDTO.JobPosting).Error; err != nil {
+	// Create the job posting with its initial status in the history
+	if err := models.CreateJobPosting(tx, &jobDTO.JobPosting, models.StatusChangeManual); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -104,6 +117,11 @@ func CreateJobPosting(c *gin.Context) {
 		}
 	}
 
+	if !saveJobSkills(c, tx, &jobDTO.JobPosting, jobDTO.SkillIDs) {
+		tx.Rollback()
+		return
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -112,7 +130,7 @@ func CreateJobPosting(c *gin.Context) {
 
 	// Return the created job with positions and optionally company
 	var createdJob models.JobPosting
-	query := DB.Preload("Positions")
+	query := DB.Preload("Positions").Preload("Skills")
 
 	// クエリパラメータを取得してcompanyを含めるかどうか決定
 	includeCompany := c.Query("include_company") == "true"
@@ -128,7 +146,8 @@ func CreateJobPosting(c *gin.Context) {
 	c.JSON(http.StatusCreated, createdJob)
 }
 
-// UpdateJobPosting updates a job posting
+// UpdateJobPosting updates a job posting.
+// Its skills are extracted again from the updated text unless they were set by hand; skill_ids sets them by hand.
 func UpdateJobPosting(c *gin.Context) {
 	id := c.Param("uuid")
 
@@ -142,12 +161,15 @@ func UpdateJobPosting(c *gin.Context) {
 	var jobDTO struct {
 		models.JobPosting
 		PositionIDs []uint `json:"position_ids"`
+		SkillIDs    []uint `json:"skill_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&jobDTO); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	jobDTO.JobPosting.Skills = nil
+	jobDTO.JobPosting.SkillsLocked = false
 
 	// Check if job exists
 	var job models.JobPosting
@@ -156,17 +178,56 @@ func UpdateJobPosting(c *gin.Context) {
 		return
 	}
 
+	// ステータスの変更は状態遷移のルールに従う
+	newStatus := job.Status
+	if jobDTO.JobPosting.Status != "" {
+		status, ok := models.ParseJobStatus(string(jobDTO.JobPosting.Status))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: " + string(jobDTO.JobPosting.Status)})
+			return
+		}
+		if status != job.Status && !job.Status.CanTransitionTo(status) {
+			respondInvalidTransition(c, &models.InvalidTransitionError{From: job.Status, To: status})
+			return
+		}
+		newStatus = status
+		jobDTO.JobPosting.Status = ""
+	}
+
 	// Start a transaction
 	tx := DB.Begin()
 
+	// 給与テキストだけが変更された場合は構造化された給与を作り直す。
+	// 上限のない範囲などゼロ値も書き込むため、4つのカラムは明示的に更新する
+	if jobDTO.JobPosting.SalaryRange != "" && !jobDTO.JobPosting.HasStructuredSalary() {
+		jobDTO.JobPosting.NormalizeSalary()
+		if err := tx.Model(&models.JobPosting{}).Where("uuid = ?", jobUUID).
+			Select("salary_min", "salary_max", "currency", "period").
+			Updates(map[string]interface{}{
+				"salary_min": jobDTO.JobPosting.SalaryMin, "salary_max": jobDTO.JobPosting.SalaryMax,
+				"currency": jobDTO.JobPosting.Currency, "period": jobDTO.JobPosting.Period,
+			}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Update job posting
 	jobDTO.JobPosting.UUID = jobUUID
+	jobDTO.JobPosting.NormalizeDates()
 	if err := tx.Model(&models.JobPosting{}).Where("uuid = ?", jobUUID).Updates(jobDTO.JobPosting).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := models.ChangeJobStatus(tx, &job, newStatus, models.StatusChangeManual); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Update positions if provided
 	if len(jobDTO.PositionIDs) > 0 {
 		// Clear existing positions
@@ -191,6 +252,25 @@ func UpdateJobPosting(c *gin.Context) {
 		}
 	}
 
+	// 更新後の内容からスキルを抽出し直す
+	var updated models.JobPosting
+	if err := tx.First(&updated, job.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveJobSkills(c, tx, &updated, jobDTO.SkillIDs) {
+		tx.Rollback()
+		return
+	}
+
+	// プロンプトに使われる項目が変わった場合はキャッシュ済みのロードマップを破棄する
+	if err := invalidateRoadmaps(tx, job.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -199,7 +279,7 @@ func UpdateJobPosting(c *gin.Context) {
 
 	// Return the updated job with positions and optionally company
 	var updatedJob models.JobPosting
-	query := DB.Preload("Positions")
+	query := DB.Preload("Positions").Preload("Skills")
 
 	// クエリパラメータを取得してcompanyを含めるかどうか決定
 	includeCompany := c.Query("include_company") == "true"
@@ -215,6 +295,26 @@ func UpdateJobPosting(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedJob)
 }
 
+// saveJobSkills sets the skills given by hand, or extracts them when skillIDs is nil.
+// It writes an error response and returns false on failure.
+func saveJobSkills(c *gin.Context, tx *gorm.DB, job *models.JobPosting, skillIDs []uint) bool {
+	var err error
+	if skillIDs != nil {
+		err = services.SetJobSkills(tx, job, skillIDs)
+	} else {
+		err = services.SyncJobSkills(tx, job)
+	}
+	if errors.Is(err, services.ErrUnknownSkill) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
 // DeleteJobPosting deletes a job posting
 func DeleteJobPosting(c *gin.Context) {
 	id := c.Param("uuid")
@@ -23,13 +23,13 @@ func setupTestRouter() (*gin.Engine, *gorm.DB) {
 	gin.SetMode(gin.TestMode)
 
 	// インメモリSQLiteデータベースを使用
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
 	if err != nil {
 		panic("テスト用データベースの接続に失敗しました: " + err.Error())
 	}
 
 	// テーブルの自動マイグレーション
-	db.AutoMigrate(&models.Company{}, &models.JobPosting{}, &models.Position{})
+	db.AutoMigrate(&models.Company{}, &models.JobPosting{}, &models.Position{}, &models.JobStatusChange{}, &models.Roadmap{}, &models.RoadmapTask{}, &models.LLMUsage{}, &models.Skill{}, &models.SkillAlias{}, &models.PositionAlias{})
 
 	// コントローラーでDBを使用できるようにする
 	DB = db
@@ -93,18 +93,22 @@ func TestGetJobPostings(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// レスポンスのボディを確認
-	var response []models.JobPosting
+	var response struct {
+		Data  []models.JobPosting `json:"data"`
+		Total int64               `json:"total"`
+	}
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
 		t.Fatalf("レスポンスのパースに失敗しました: %v", err)
 	}
 
 	// 少なくとも1件のジョブポスティングが返されていることを確認
-	assert.True(t, len(response) > 0)
+	assert.True(t, len(response.Data) > 0)
+	assert.Equal(t, int64(len(response.Data)), response.Total)
 
 	// 作成したジョブポスティングが含まれていることを確認
 	found := false
-	for _, j := range response {
+	for _, j := range response.Data {
 		if j.UUID == job.UUID {
 			found = true
 			assert.Equal(t, "テスト求人", j.Title)
@@ -209,7 +213,62 @@ func TestCreateJobPosting(t *testing.T) {
 	assert.Equal(t, "フルリモート", response.Location)
 	assert.NotEqual(t, uuid.Nil, response.UUID)
 
+	// 掲載日と締切日が保存されていることを確認
+	if assert.NotNil(t, response.PostingDate) && assert.NotNil(t, response.ClosingDate) {
+		assert.True(t, reqBody.ClosingDate.Equal(*response.ClosingDate))
+	}
+
 	// ポジションが関連付けられていることを確認
 	assert.Equal(t, 1, len(response.Positions))
 	assert.Equal(t, "フロントエンドエンジニア", response.Positions[0].Name)
 }
+
+// TestUpdateJobPostingSalary は給与テキストの更新で構造化された給与も更新されることをテストします
+func TestUpdateJobPostingSalary(t *testing.T) {
+	r, db := setupTestRouter()
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	assert.Equal(t, int64(6000000), job.SalaryMin)
+
+	r.PUT("/api/v1/jobs/:uuid", UpdateJobPosting)
+
+	body, _ := json.Marshal(map[string]string{"salary_range": "月給40万円〜50万円"})
+	req, _ := http.NewRequest("PUT", "/api/v1/jobs/"+job.UUID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.JobPosting
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(400000), response.SalaryMin)
+	assert.Equal(t, int64(500000), response.SalaryMax)
+	assert.Equal(t, models.SalaryPeriodMonth, response.Period)
+
+	// 上限のない範囲に変更した場合は以前の上限を残さない
+	body, _ = json.Marshal(map[string]string{"salary_range": "年収500万円以上"})
+	req, _ = http.NewRequest("PUT", "/api/v1/jobs/"+job.UUID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stored models.JobPosting
+	db.First(&stored, job.ID)
+	assert.Equal(t, int64(5000000), stored.SalaryMin)
+	assert.Equal(t, int64(0), stored.SalaryMax)
+	assert.Equal(t, models.SalaryPeriodYear, stored.Period)
+
+	// パースできない給与テキストに変更した場合は構造化された給与をクリアする
+	body, _ = json.Marshal(map[string]string{"salary_range": "応相談"})
+	req, _ = http.NewRequest("PUT", "/api/v1/jobs/"+job.UUID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "応相談", response.SalaryRange)
+	assert.Equal(t, int64(0), response.SalaryMin)
+	assert.Equal(t, "", response.Period)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"howtv-server/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// jobStatusInfo describes a status and the statuses it can change to
+type jobStatusInfo struct {
+	Status      models.JobStatus   `json:"status"`
+	Label       string             `json:"label"`
+	Transitions []models.JobStatus `json:"transitions"`
+}
+
+// respondInvalidTransition writes a 409 response for a status change the lifecycle does not allow
+func respondInvalidTransition(c *gin.Context, err *models.InvalidTransitionError) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":               err.Error(),
+		"status":              err.From,
+		"allowed_transitions": err.From.Transitions(),
+	})
+}
+
+// GetJobStatuses returns the job statuses with their display labels (lang=ja|en) and allowed transitions
+func GetJobStatuses(c *gin.Context) {
+	lang := c.DefaultQuery("lang", "ja")
+
+	statuses := make([]jobStatusInfo, 0, len(models.JobStatuses))
+	for _, status := range models.JobStatuses {
+		statuses = append(statuses, jobStatusInfo{
+			Status:      status,
+			Label:       status.Label(lang),
+			Transitions: status.Transitions(),
+		})
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+// PublishJobPosting moves a job posting to published
+func PublishJobPosting(c *gin.Context) {
+	changeJobStatus(c, models.JobStatusPublished)
+}
+
+// PauseJobPosting moves a job posting to paused
+func PauseJobPosting(c *gin.Context) {
+	changeJobStatus(c, models.JobStatusPaused)
+}
+
+// CloseJobPosting moves a job posting to closed
+func CloseJobPosting(c *gin.Context) {
+	changeJobStatus(c, models.JobStatusClosed)
+}
+
+func changeJobStatus(c *gin.Context, to models.JobStatus) {
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var job models.JobPosting
+	if err := DB.Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job posting not found"})
+		return
+	}
+
+	tx := DB.Begin()
+	if err := models.ChangeJobStatus(tx, &job, to, models.StatusChangeManual); err != nil {
+		tx.Rollback()
+		var transitionErr *models.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			respondInvalidTransition(c, transitionErr)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	var updatedJob models.JobPosting
+	if err := DB.Preload("Positions").Preload("Skills").First(&updatedJob, job.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Job updated but failed to retrieve it"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedJob)
+}
+
+// GetJobStatusHistory returns the status changes of a job posting, oldest first
+func GetJobStatusHistory(c *gin.Context) {
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var job models.JobPosting
+	if err := DB.Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job posting not found"})
+		return
+	}
+
+	var changes []models.JobStatusChange
+	if err := DB.Where("job_posting_id = ?", job.ID).Order("changed_at, id").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"howtv-server/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJobStatusLifecycle は状態遷移のエンドポイントと履歴をテストします
+func TestJobStatusLifecycle(t *testing.T) {
+	r, db := setupTestRouter()
+	r.POST("/api/v1/jobs", CreateJobPosting)
+	r.PUT("/api/v1/jobs/:uuid", UpdateJobPosting)
+	r.POST("/api/v1/jobs/:uuid/publish", PublishJobPosting)
+	r.POST("/api/v1/jobs/:uuid/pause", PauseJobPosting)
+	r.POST("/api/v1/jobs/:uuid/close", CloseJobPosting)
+	r.GET("/api/v1/jobs/:uuid/status-history", GetJobStatusHistory)
+
+	// ステータス未指定の場合は下書きとして作成される
+	w := performJSONRequest(r, "POST", "/api/v1/jobs", map[string]string{"title": "ステータステスト"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var job models.JobPosting
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.Equal(t, models.JobStatusDraft, job.Status)
+	path := "/api/v1/jobs/" + job.UUID.String()
+
+	// 下書きから一時停止には遷移できない
+	w = performJSONRequest(r, "POST", path+"/pause", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Status  models.JobStatus   `json:"status"`
+		Allowed []models.JobStatus `json:"allowed_transitions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(t, models.JobStatusDraft, conflict.Status)
+	assert.Equal(t, []models.JobStatus{models.JobStatusPublished, models.JobStatusArchived}, conflict.Allowed)
+
+	for _, action := range []struct {
+		path     string
+		expected models.JobStatus
+	}{
+		{"/publish", models.JobStatusPublished},
+		{"/pause", models.JobStatusPaused},
+		{"/close", models.JobStatusClosed},
+	} {
+		w = performJSONRequest(r, "POST", path+action.path, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &job)
+		assert.Equal(t, action.expected, job.Status)
+	}
+
+	// PUTでの変更も状態遷移のルールに従う
+	w = performJSONRequest(r, "PUT", path, map[string]string{"status": "paused"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performJSONRequest(r, "PUT", path, map[string]string{"status": "unknown"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performJSONRequest(r, "PUT", path, map[string]string{"status": "archived"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// ステータス以外の更新ではステータスは変わらない
+	w = performJSONRequest(r, "PUT", path, map[string]string{"title": "更新後"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.Equal(t, models.JobStatusArchived, job.Status)
+
+	var stored models.JobPosting
+	db.First(&stored, job.ID)
+	assert.Equal(t, models.JobStatusArchived, stored.Status)
+
+	w = performJSONRequest(r, "GET", path+"/status-history", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history []models.JobStatusChange
+	json.Unmarshal(w.Body.Bytes(), &history)
+	var statuses []models.JobStatus
+	for _, change := range history {
+		statuses = append(statuses, change.ToStatus)
+	}
+	assert.Equal(t, []models.JobStatus{
+		models.JobStatusDraft, models.JobStatusPublished, models.JobStatusPaused,
+		models.JobStatusClosed, models.JobStatusArchived,
+	}, statuses)
+}
+
+// TestGetJobStatuses はステータス一覧のラベルをテストします
+func TestGetJobStatuses(t *testing.T) {
+	r, _ := setupTestRouter()
+	r.GET("/api/v1/job-statuses", GetJobStatuses)
+
+	w := performJSONRequest(r, "GET", "/api/v1/job-statuses?lang=en", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var statuses []jobStatusInfo
+	json.Unmarshal(w.Body.Bytes(), &statuses)
+	assert.Len(t, statuses, len(models.JobStatuses))
+	assert.Equal(t, "Published", statuses[1].Label)
+	assert.Empty(t, statuses[4].Transitions)
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"howtv-server/services"
+)
+
+// 期間の指定に使う日付の形式
+const usageDateLayout = "2006-01-02"
+
+// GetLLMUsage returns the LLM usage aggregated by day, job and question type.
+// from and to (YYYY-MM-DD, both inclusive) default to the current month. The response also reports the monthly budget.
+func GetLLMUsage(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if s := c.Query("from"); s != "" {
+		d, err := time.ParseInLocation(usageDateLayout, s, now.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+		from = d
+	}
+	if s := c.Query("to"); s != "" {
+		d, err := time.ParseInLocation(usageDateLayout, s, now.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		to = d
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	// to はその日の終わりまでを含める
+	summary, err := services.SummarizeUsage(DB, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := gin.H{
+		"from":             from.Format(usageDateLayout),
+		"to":               to.Format(usageDateLayout),
+		"total":            summary.Total,
+		"by_day":           summary.ByDay,
+		"by_job":           summary.ByJob,
+		"by_question_type": summary.ByQuestionType,
+	}
+	if Usage != nil {
+		monthCost, err := Usage.MonthlyCost()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		budget := gin.H{"monthly_budget": Usage.MonthlyBudget(), "month_cost": monthCost}
+		if Usage.MonthlyBudget() > 0 {
+			budget["remaining"] = max(Usage.MonthlyBudget()-monthCost, 0)
+			budget["exceeded"] = monthCost >= Usage.MonthlyBudget()
+		}
+		result["budget"] = budget
+	}
+	c.JSON(http.StatusOK, result)
+}
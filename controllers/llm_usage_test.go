@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/services"
+)
+
+// TestGetLLMUsage はLLMの利用量の集計と予算の超過をテストします
+func TestGetLLMUsage(t *testing.T) {
+	r, db := setupTestRouter()
+	Usage = services.NewUsageTracker(db, services.UsageConfig{
+		MonthlyBudget: 0.0001,
+		Prices:        map[string]services.ModelPrice{services.ProviderStub: {Input: 1, Output: 1}},
+	})
+	Roadmaps = services.NewRoadmapService(services.NewStubProvider()).TrackUsage(Usage)
+	defer func() { Roadmaps, Usage = nil, nil }()
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+	r.GET("/api/v1/admin/llm-usage", GetLLMUsage)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap"
+
+	w := performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var usage struct {
+		Total struct {
+			Calls int64 `json:"calls"`
+		} `json:"total"`
+		ByJob []struct {
+			JobTitle string `json:"job_title"`
+		} `json:"by_job"`
+		ByQuestionType []struct {
+			Key string `json:"key"`
+		} `json:"by_question_type"`
+		Budget struct {
+			MonthlyBudget float64 `json:"monthly_budget"`
+			Exceeded      bool    `json:"exceeded"`
+		} `json:"budget"`
+	}
+	w = performJSONRequest(r, "GET", "/api/v1/admin/llm-usage", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &usage)
+	assert.Equal(t, int64(1), usage.Total.Calls)
+	if assert.Len(t, usage.ByJob, 1) {
+		assert.Equal(t, "テスト求人", usage.ByJob[0].JobTitle)
+	}
+	if assert.Len(t, usage.ByQuestionType, 1) {
+		assert.Equal(t, "general", usage.ByQuestionType[0].Key)
+	}
+	assert.Equal(t, 0.0001, usage.Budget.MonthlyBudget)
+	assert.True(t, usage.Budget.Exceeded)
+
+	// 予算を超えた後はキャッシュ済みのロードマップのみ返す
+	var roadmap struct {
+		Cached bool   `json:"cached"`
+		Code   string `json:"code"`
+	}
+	w = performJSONRequest(r, "GET", path+"?refresh=true", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &roadmap)
+	assert.True(t, roadmap.Cached)
+	w = performJSONRequest(r, "GET", path+"?question_type=skills", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &roadmap)
+	assert.Equal(t, services.ErrorCodeBudgetExceeded, roadmap.Code)
+
+	w = performJSONRequest(r, "GET", "/api/v1/admin/llm-usage?from=2020-01-01&to=2020-01-31", nil)
+	json.Unmarshal(w.Body.Bytes(), &usage)
+	assert.Equal(t, int64(0), usage.Total.Calls)
+
+	w = performJSONRequest(r, "GET", "/api/v1/admin/llm-usage?from=2020-13-01", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// sortSpec describes a column that list endpoints can be sorted by
+type sortSpec struct {
+	Column      string
+	DefaultDesc bool
+	// Parse はカーソルに保存した文字列をクエリ用の値に戻す（nilなら文字列のまま）
+	Parse func(string) (interface{}, error)
+}
+
+// pageCursor identifies the last row of a page for keyset pagination
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// pageParams holds the pagination and sorting options of a list request
+type pageParams struct {
+	Limit  int
+	Page   int
+	Cursor *pageCursor
+	Sort   string
+	Desc   bool
+	spec   sortSpec
+}
+
+type pageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// pageResponse is the envelope returned by paginated list endpoints
+type pageResponse struct {
+	Data       interface{} `json:"data"`
+	Total      int64       `json:"total"`
+	Limit      int         `json:"limit"`
+	Page       int         `json:"page,omitempty"`
+	NextCursor *string     `json:"next_cursor"`
+	Links      pageLinks   `json:"links"`
+}
+
+func encodeCursor(cur pageCursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// parsePageParams reads the limit, page, cursor, sort and order query parameters.
+// cursor and page are mutually exclusive; when a cursor is given keyset pagination is used.
+func parsePageParams(c *gin.Context, sorts map[string]sortSpec, defaultSort string) (pageParams, error) {
+	params := pageParams{Limit: defaultPageLimit, Page: 1, Sort: defaultSort}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return params, errors.New("limit must be a positive integer")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		params.Limit = limit
+	}
+
+	if v := c.Query("sort"); v != "" {
+		params.Sort = v
+	}
+	spec, ok := sorts[params.Sort]
+	if !ok {
+		names := make([]string, 0, len(sorts))
+		for name := range sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return params, fmt.Errorf("sort must be one of: %s", strings.Join(names, ", "))
+	}
+	params.spec = spec
+
+	switch c.Query("order") {
+	case "":
+		params.Desc = spec.DefaultDesc
+	case "asc":
+		params.Desc = false
+	case "desc":
+		params.Desc = true
+	default:
+		return params, errors.New("order must be asc or desc")
+	}
+
+	cursor := c.Query("cursor")
+	page := c.Query("page")
+	if cursor != "" && page != "" {
+		return params, errors.New("cursor and page cannot be used together")
+	}
+	if cursor != "" {
+		cur, err := decodeCursor(cursor)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cur
+		params.Page = 0
+	}
+	if page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p < 1 {
+			return params, errors.New("page must be a positive integer")
+		}
+		params.Page = p
+	}
+
+	return params, nil
+}
+
+// apply adds ordering, the keyset condition or offset, and a limit of Limit+1 to the query.
+// The extra row tells whether there is a next page.
+func (p pageParams) apply(query *gorm.DB, table string) (*gorm.DB, error) {
+	column := table + "." + p.spec.Column
+	idColumn := table + ".id"
+
+	direction, cmp := "ASC", ">"
+	if p.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if p.Cursor != nil {
+		var value interface{} = p.Cursor.Value
+		if p.spec.Parse != nil {
+			parsed, err := p.spec.Parse(p.Cursor.Value)
+			if err != nil {
+				return nil, errors.New("invalid cursor")
+			}
+			value = parsed
+		}
+		query = query.Where(
+			fmt.Sprintf("((%s %s ?) OR (%s = ? AND %s %s ?))", column, cmp, column, idColumn, cmp),
+			value, value, p.Cursor.ID,
+		)
+	} else if p.Page > 1 {
+		query = query.Offset((p.Page - 1) * p.Limit)
+	}
+
+	return query.Order(column + " " + direction).Order(idColumn + " " + direction).Limit(p.Limit + 1), nil
+}
+
+// links builds self/next/prev links that keep the other query parameters of the request
+func (p pageParams) links(c *gin.Context, nextCursor *string, hasMore bool) pageLinks {
+	build := func(key, value string) string {
+		u := *c.Request.URL
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	links := pageLinks{Self: c.Request.URL.RequestURI()}
+	if p.Cursor != nil {
+		if hasMore && nextCursor != nil {
+			links.Next = build("cursor", *nextCursor)
+		}
+		return links
+	}
+
+	if hasMore {
+		links.Next = build("page", strconv.Itoa(p.Page+1))
+	}
+	if p.Page > 1 {
+		links.Prev = build("page", strconv.Itoa(p.Page-1))
+	}
+	return links
+}
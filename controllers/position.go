@@ -1,37 +1,402 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"howtv-server/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// positionInput is the request body accepted by CreatePosition and UpdatePosition
+type positionInput struct {
+	Name     string       `json:"name" binding:"required,max=100"`
+	NameJa   string       `json:"name_ja" binding:"max=100"`
+	NameEn   string       `json:"name_en" binding:"max=100"`
+	ParentID *uint        `json:"parent_id"`
+	Aliases  []aliasInput `json:"aliases" binding:"dive"`
+}
+
+// bindPositionInput reads and validates the body and writes a 400 response if it is invalid
+func bindPositionInput(c *gin.Context) (positionInput, bool) {
+	var input positionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return input, false
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	input.NameJa = strings.TrimSpace(input.NameJa)
+	input.NameEn = strings.TrimSpace(input.NameEn)
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return input, false
+	}
+	return input, true
+}
+
+// position builds the position from the input. Aliases that only repeat a name or another alias are dropped.
+func (input positionInput) position() models.Position {
+	position := models.Position{Name: input.Name, NameJa: input.NameJa, NameEn: input.NameEn, ParentID: input.ParentID}
+	seen := map[string]bool{}
+	for _, term := range position.Terms() {
+		seen[term] = true
+	}
+	position.Aliases = []models.PositionAlias{}
+	for _, alias := range input.Aliases {
+		term := models.NormalizeSkillTerm(alias.Name)
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		position.Aliases = append(position.Aliases, models.PositionAlias{Name: strings.TrimSpace(alias.Name), Lang: alias.Lang})
+	}
+	return position
+}
+
+// parsePositionID reads the :id path parameter and writes a 400 response if it is invalid
+func parsePositionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid position ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// findPosition loads a position with its aliases and writes a 404 response if it does not exist
+func findPosition(c *gin.Context, id uint) (models.Position, bool) {
+	var position models.Position
+	if err := DB.Preload("Aliases").First(&position, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Position not found"})
+		return position, false
+	}
+	return position, true
+}
+
+// checkPositionTerms writes a 409 response if another position already uses a name or alias of position.
+// The name has a unique index, so the database would otherwise reject it with a driver specific error,
+// and lookups by name or alias have to find a single position.
+func checkPositionTerms(c *gin.Context, position *models.Position, excludeID uint) bool {
+	var others []models.Position
+	query := DB.Preload("Aliases")
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Find(&others).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	taken := map[string]bool{}
+	for i := range others {
+		for _, term := range others[i].Terms() {
+			taken[term] = true
+		}
+	}
+	for _, term := range position.Terms() {
+		if taken[term] {
+			respondPositionConflict(c, term)
+			return false
+		}
+	}
+	return true
+}
+
+// respondPositionConflict writes the 409 response for a name or alias that another position uses
+func respondPositionConflict(c *gin.Context, term string) {
+	c.JSON(http.StatusConflict, gin.H{"error": "Position with this name already exists", "term": term})
+}
+
+// checkPositionParent writes a 400 response if the parent does not exist or would make the tree a cycle
+func checkPositionParent(c *gin.Context, parentID *uint, positionID uint) bool {
+	if parentID == nil {
+		return true
+	}
+	var parent models.Position
+	if err := DB.First(&parent, *parentID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent position not found"})
+		return false
+	}
+	if positionID == 0 {
+		return true
+	}
+
+	subtree, err := models.PositionSubtreeIDs(DB, []uint{positionID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	for _, id := range subtree {
+		if id == parent.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A position cannot be moved under itself or its descendants"})
+			return false
+		}
+	}
+	return true
+}
+
+// positionJobIDs returns the IDs of the job postings that have the position
+func positionJobIDs(db *gorm.DB, positionID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Table("job_positions").Where("position_id = ?", positionID).Pluck("job_posting_id", &ids).Error
+	return ids, err
+}
+
+// positionSubtreeJobs returns a subquery of the IDs of the job postings that have one of the positions or their descendants
+func positionSubtreeJobs(positionIDs []uint) (*gorm.DB, error) {
+	subtree, err := models.PositionSubtreeIDs(DB, positionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return DB.Table("job_positions").Select("job_posting_id").Where("position_id IN ?", subtree), nil
+}
+
+// invalidatePositionRoadmaps marks the roadmaps of the jobs stale, since the position names are part of the prompt
+func invalidatePositionRoadmaps(db *gorm.DB, jobIDs []uint) error {
+	for _, jobID := range jobIDs {
+		if err := invalidateRoadmaps(db, jobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetPositions(c *gin.Context) {
 	var positions []models.Position
-	if err := DB.Find(&positions).Error; err != nil {
+	if err := DB.Preload("Aliases").Order("id").Find(&positions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, positions)
 }
 
+// GetPositionTree returns the positions nested under their parents
+func GetPositionTree(c *gin.Context) {
+	var positions []models.Position
+	if err := DB.Preload("Aliases").Order("id").Find(&positions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, buildPositionTree(positions))
+}
+
+// buildPositionTree nests the positions under their parents. Positions whose parent is missing become roots.
+func buildPositionTree(positions []models.Position) []models.Position {
+	children := map[uint][]int{}
+	exists := make(map[uint]bool, len(positions))
+	for _, p := range positions {
+		exists[p.ID] = true
+	}
+	var roots []int
+	for i, p := range positions {
+		if p.ParentID != nil && exists[*p.ParentID] && *p.ParentID != p.ID {
+			children[*p.ParentID] = append(children[*p.ParentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var build func(i int, depth int) models.Position
+	build = func(i int, depth int) models.Position {
+		node := positions[i]
+		node.Children = []models.Position{}
+		// 壊れたparent_idの循環で無限に再帰しないようにする
+		if depth > len(positions) {
+			return node
+		}
+		for _, child := range children[node.ID] {
+			node.Children = append(node.Children, build(child, depth+1))
+		}
+		return node
+	}
+
+	tree := make([]models.Position, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i, 0))
+	}
+	return tree
+}
+
+// GetPosition returns a single position
+func GetPosition(c *gin.Context) {
+	id, ok := parsePositionID(c)
+	if !ok {
+		return
+	}
+
+	position, ok := findPosition(c, id)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, position)
+}
+
 func CreatePosition(c *gin.Context) {
-	var position models.Position
-	if err := c.ShouldBindJSON(&position); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	input, ok := bindPositionInput(c)
+	if !ok {
+		return
+	}
+
+	position := input.position()
+	if !checkPositionParent(c, position.ParentID, 0) || !checkPositionTerms(c, &position, 0) {
 		return
 	}
 
 	if err := DB.Create(&position).Error; err != nil {
+		// 事前の確認の後に同じ名前が作成された場合も一意制約の違反は409にする
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondPositionConflict(c, models.NormalizeSkillTerm(position.Name))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, position)
 }
 
+// UpdatePosition replaces the names, parent and aliases of a position
+func UpdatePosition(c *gin.Context) {
+	id, ok := parsePositionID(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindPositionInput(c)
+	if !ok {
+		return
+	}
+
+	existing, ok := findPosition(c, id)
+	if !ok {
+		return
+	}
+
+	position := input.position()
+	if !checkPositionParent(c, position.ParentID, existing.ID) || !checkPositionTerms(c, &position, existing.ID) {
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existing).Select("Name", "NameJa", "NameEn", "ParentID").Updates(models.Position{
+			Name: position.Name, NameJa: position.NameJa, NameEn: position.NameEn, ParentID: position.ParentID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("position_id = ?", existing.ID).Delete(&models.PositionAlias{}).Error; err != nil {
+			return err
+		}
+		for i := range position.Aliases {
+			position.Aliases[i].PositionID = existing.ID
+		}
+		if len(position.Aliases) > 0 {
+			if err := tx.Create(&position.Aliases).Error; err != nil {
+				return err
+			}
+		}
+		jobIDs, err := positionJobIDs(tx, existing.ID)
+		if err != nil {
+			return err
+		}
+		return invalidatePositionRoadmaps(tx, jobIDs)
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		respondPositionConflict(c, models.NormalizeSkillTerm(position.Name))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, ok := findPosition(c, existing.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeletePosition deletes a position. Its children move up to its parent.
+// Positions that are still assigned to job postings are only deleted when cascade=true is given,
+// in which case they are removed from those job postings; the job postings themselves are kept.
+func DeletePosition(c *gin.Context) {
+	id, ok := parsePositionID(c)
+	if !ok {
+		return
+	}
+
+	position, ok := findPosition(c, id)
+	if !ok {
+		return
+	}
+
+	jobIDs, err := positionJobIDs(DB, position.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cascade := c.Query("cascade") == "true"
+	if len(jobIDs) > 0 && !cascade {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Position is assigned to job postings; pass cascade=true to remove it from them",
+			"job_count": len(jobIDs),
+		})
+		return
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Position{}).Where("parent_id = ?", position.ID).Update("parent_id", position.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&position).Association("Jobs").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("position_id = ?", position.ID).Delete(&models.PositionAlias{}).Error; err != nil {
+			return err
+		}
+		// 論理削除だと名前のユニークインデックスが残り、同じ名前で作り直せなくなる
+		if err := tx.Unscoped().Delete(&position).Error; err != nil {
+			return err
+		}
+		return invalidatePositionRoadmaps(tx, jobIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Position deleted successfully",
+		"detached_jobs": len(jobIDs),
+	})
+}
+
+// GetPositionJobs returns a page of the job postings that have a position or one of its descendants,
+// so that the jobs of a category such as "エンジニアリング" include those of "バックエンドエンジニア"
+func GetPositionJobs(c *gin.Context) {
+	id, ok := parsePositionID(c)
+	if !ok {
+		return
+	}
+
+	position, ok := findPosition(c, id)
+	if !ok {
+		return
+	}
+
+	jobs, err := positionSubtreeJobs([]uint{position.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondJobPage(c, DB.Model(&models.JobPosting{}).Where("job_postings.id IN (?)", jobs))
+}
+
 func AssignPositionsToJob(c *gin.Context) {
 	id := c.Param("uuid")
 
@@ -69,5 +434,10 @@ func AssignPositionsToJob(c *gin.Context) {
 		}
 	}
 
+	if err := invalidateRoadmaps(DB, job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Positions assigned successfully"})
 }
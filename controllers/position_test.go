@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// registerPositionRoutes はポジションAPIのハンドラーをルーターに登録します
+func registerPositionRoutes(r *gin.Engine) {
+	r.GET("/api/v1/positions", GetPositions)
+	r.GET("/api/v1/positions/:id", GetPosition)
+	r.POST("/api/v1/positions", CreatePosition)
+	r.PUT("/api/v1/positions/:id", UpdatePosition)
+	r.DELETE("/api/v1/positions/:id", DeletePosition)
+	r.GET("/api/v1/positions/:id/jobs", GetPositionJobs)
+}
+
+// insertBeforeWrite は次に table へ書き込む直前に同じ名前の行を挿入し、
+// 事前の重複確認の後に別のリクエストが同じ名前を作成した場合を再現します
+func insertBeforeWrite(t *testing.T, db *gorm.DB, table, name string) {
+	inserted := false
+	createHook, updateHook := "test:insert_before_create:"+name, "test:insert_before_update:"+name
+	hook := func(tx *gorm.DB) {
+		if inserted || tx.Statement.Table != table {
+			return
+		}
+		inserted = true
+		if err := tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO "+table+" (name) VALUES (?)", name).Error; err != nil {
+			t.Errorf("行を挿入できません: %v", err)
+		}
+	}
+	// コールバックはテストごとに開くデータベースにだけ登録される
+	db.Callback().Create().Before("gorm:create").Register(createHook, hook)
+	db.Callback().Update().Before("gorm:update").Register(updateHook, hook)
+}
+
+// TestPositionConcurrentDuplicate は事前の確認をすり抜けた重複も409になることをテストします
+func TestPositionConcurrentDuplicate(t *testing.T) {
+	r, db := setupTestRouter()
+	registerPositionRoutes(r)
+
+	w := performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "バックエンドエンジニア"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var position models.Position
+	json.Unmarshal(w.Body.Bytes(), &position)
+
+	insertBeforeWrite(t, db, "positions", "プラットフォームエンジニア")
+	w = performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "プラットフォームエンジニア"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already exists")
+	assert.NotContains(t, w.Body.String(), "UNIQUE")
+
+	insertBeforeWrite(t, db, "positions", "SREエンジニア")
+	w = performJSONRequest(r, "PUT", fmt.Sprintf("/api/v1/positions/%d", position.ID), gin.H{"name": "SREエンジニア"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already exists")
+}
+
+// TestPositionCRUD はポジションの作成・取得・更新と名前の重複をテストします
+func TestPositionCRUD(t *testing.T) {
+	r, _ := setupTestRouter()
+	registerPositionRoutes(r)
+
+	w := performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "バックエンドエンジニヤ"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.Position
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	// 重複する名前は生のSQLエラーではなく409
+	w = performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "バックエンドエンジニヤ"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already exists")
+
+	w = performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": " "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 名前の誤字を直す
+	path := fmt.Sprintf("/api/v1/positions/%d", created.ID)
+	w = performJSONRequest(r, "PUT", path, gin.H{"name": "バックエンドエンジニア"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var position models.Position
+	json.Unmarshal(w.Body.Bytes(), &position)
+	assert.Equal(t, "バックエンドエンジニア", position.Name)
+
+	performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "インフラエンジニア"})
+	w = performJSONRequest(r, "PUT", path, gin.H{"name": "インフラエンジニア"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performJSONRequest(r, "GET", "/api/v1/positions/9999", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performJSONRequest(r, "GET", "/api/v1/positions/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestDeletePositionWithJobs は求人に割り当てられたポジションの削除と求人一覧をテストします
+func TestDeletePositionWithJobs(t *testing.T) {
+	r, db := setupTestRouter()
+	registerPositionRoutes(r)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	var position models.Position
+	db.Where("name = ?", "フロントエンドエンジニア").First(&position)
+	path := fmt.Sprintf("/api/v1/positions/%d", position.ID)
+
+	// ポジションの求人一覧
+	w := performJSONRequest(r, "GET", path+"/jobs", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var jobs jobListResponse
+	json.Unmarshal(w.Body.Bytes(), &jobs)
+	assert.Equal(t, []string{"テスト求人"}, jobTitles(jobs))
+
+	// cascade なしでは削除できない
+	w = performJSONRequest(r, "DELETE", path, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// cascade=true で求人から外して削除する。求人は残る
+	w = performJSONRequest(r, "DELETE", path+"?cascade=true", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var reloaded models.JobPosting
+	db.Preload("Positions").First(&reloaded, job.ID)
+	assert.Empty(t, reloaded.Positions)
+
+	w = performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 削除した名前で作り直せる
+	w = performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "フロントエンドエンジニア"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+// TestPositionTree はポジションの木・別名・カテゴリによる絞り込みをテストします
+func TestPositionTree(t *testing.T) {
+	r, db := setupTestRouter()
+	registerPositionRoutes(r)
+	r.GET("/api/v1/positions/tree", GetPositionTree)
+	r.GET("/api/v1/jobs", GetJobPostings)
+
+	create := func(body gin.H) models.Position {
+		w := performJSONRequest(r, "POST", "/api/v1/positions", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("ポジションの作成に失敗しました: %d %s", w.Code, w.Body.String())
+		}
+		var position models.Position
+		json.Unmarshal(w.Body.Bytes(), &position)
+		return position
+	}
+	engineering := create(gin.H{"name": "エンジニアリング", "name_en": "Engineering"})
+	backend := create(gin.H{
+		"name": "バックエンドエンジニア", "name_en": "Backend Engineer", "parent_id": engineering.ID,
+		"aliases": []gin.H{{"name": "サーバーサイドエンジニア", "lang": "ja"}},
+	})
+	sales := create(gin.H{"name": "営業"})
+
+	// 存在しない親は400
+	w := performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "迷子", "parent_id": 9999})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 別名や表示名が他のポジションと重複する場合は409
+	w = performJSONRequest(r, "POST", "/api/v1/positions", gin.H{"name": "backend engineer"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 自身の配下に移動すると循環するので400
+	w = performJSONRequest(r, "PUT", fmt.Sprintf("/api/v1/positions/%d", engineering.ID), gin.H{"name": "エンジニアリング", "parent_id": backend.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performJSONRequest(r, "GET", "/api/v1/positions/tree", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tree []models.Position
+	json.Unmarshal(w.Body.Bytes(), &tree)
+	if assert.Len(t, tree, 2) {
+		assert.Equal(t, "エンジニアリング", tree[0].Name)
+		if assert.Len(t, tree[0].Children, 1) {
+			assert.Equal(t, "バックエンドエンジニア", tree[0].Children[0].Name)
+		}
+	}
+
+	backendJob := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&backendJob)
+	db.Model(&backendJob).Association("Positions").Append(&backend)
+	salesJob := models.JobPosting{Title: "法人営業"}
+	db.Create(&salesJob)
+	db.Model(&salesJob).Association("Positions").Append(&sales)
+
+	// カテゴリで絞り込むと配下のポジションの求人も含まれる
+	w = performJSONRequest(r, "GET", fmt.Sprintf("/api/v1/positions/%d/jobs", engineering.ID), nil)
+	var page jobListResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(page))
+
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(getJobList(t, r, fmt.Sprintf("/api/v1/jobs?position_id=%d", engineering.ID))))
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(getJobList(t, r, "/api/v1/jobs?position=Engineering")))
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(getJobList(t, r, "/api/v1/jobs?position=サーバーサイドエンジニア")))
+	w = performJSONRequest(r, "GET", "/api/v1/jobs?position=unknown", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 削除すると子は親の位置に繰り上がる
+	w = performJSONRequest(r, "DELETE", fmt.Sprintf("/api/v1/positions/%d", engineering.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var moved models.Position
+	db.First(&moved, backend.ID)
+	assert.Nil(t, moved.ParentID)
+}
@@ -1,50 +1,315 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
-	"howtv-server/config"
 	"howtv-server/models"
 	"howtv-server/services"
 )
 
 func GenerateRoadmap(c *gin.Context) {
-	id := c.Param("uuid")
-	questionType := c.DefaultQuery("question_type", "general") // 新規追加：質問タイプのクエリパラメータ
+	opts, ok := roadmapOptions(c)
+	if !ok {
+		return
+	}
+	respondRoadmap(c, opts)
+}
+
+// GeneratePersonalizedRoadmap generates a roadmap for the gap between the candidate profile in the body and the job.
+// It accepts the same query parameters as GenerateRoadmap and the response also includes skill_gaps.
+func GeneratePersonalizedRoadmap(c *gin.Context) {
+	opts, ok := roadmapOptions(c)
+	if !ok {
+		return
+	}
 
-	jobUUID, err := uuid.Parse(id)
+	var profile services.CandidateProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.Message(opts.Lang, "invalid_profile"), "detail": err.Error()})
+		return
+	}
+	if err := profile.Validate(time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.Message(opts.Lang, "invalid_profile"), "detail": err.Error()})
+		return
+	}
+	opts.Profile = &profile
+
+	respondRoadmap(c, opts)
+}
+
+// respondRoadmap responds with the cached or newly generated roadmap of the job
+func respondRoadmap(c *gin.Context, opts services.RoadmapOptions) {
+	refresh := c.Query("refresh") == "true" // キャッシュを使わずに再生成する
+
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "無効なUUID形式です"})
+		roadmapError(c, http.StatusBadRequest, opts.Lang, "invalid_uuid")
 		return
 	}
 
 	var job models.JobPosting
 	if err := DB.Preload("Positions").Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "求人情報が見つかりませんでした"})
+		roadmapError(c, http.StatusNotFound, opts.Lang, "job_not_found")
+		return
+	}
+	// プロンプトで強調する専門分野は職種の上位のカテゴリからも決まる
+	if err := models.LoadPositionParents(DB, job.Positions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	apiKey := config.GetOpenAIAPIKey()
-	if apiKey == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "OpenAI APIキーが設定されていません"})
+	if Roadmaps == nil {
+		roadmapError(c, http.StatusInternalServerError, opts.Lang, "service_not_configured")
 		return
 	}
 
-	openaiService := services.NewOpenAIService(apiKey)
+	roadmap, cached, err := services.GetOrGenerateRoadmap(c.Request.Context(), DB, Roadmaps, &job, opts, refresh)
+	if err != nil {
+		status, code := roadmapErrorStatus(err)
+		c.JSON(status, generationError(opts.Lang, code, err))
+		return
+	}
 
-	roadmap, err := openaiService.GenerateCareerRoadmap(&job, questionType)
+	result, err := roadmapResult(c, &job, opts.QuestionType, roadmap, cached)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, result)
+}
+
+// roadmapOptions reads the question_type, lang, template, seed and model query parameters and responds with 400 when they are invalid.
+// lang defaults to the best match for Accept-Language. template selects a prompt template by ID and seed selects one
+// deterministically; by default the question type's default template is used. model selects an allowlisted model.
+func roadmapOptions(c *gin.Context) (services.RoadmapOptions, bool) {
+	opts := services.RoadmapOptions{
+		QuestionType: c.DefaultQuery("question_type", "general"), // 新規追加：質問タイプのクエリパラメータ
+		TemplateID:   c.Query("template"),
+		Model:        c.Query("model"),
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	lang, ok := roadmapLanguage(c, c.Query("lang"))
+	opts.Lang = lang
+	if !ok {
+		return opts, false
+	}
+
+	if s := c.Query("seed"); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			roadmapError(c, http.StatusBadRequest, opts.Lang, "invalid_seed", s)
+			return opts, false
+		}
+		opts.Seed = &seed
+	}
+	return opts, validateRoadmapOptions(c, opts)
+}
+
+// validateRoadmapOptions rejects an unknown template or a model that is not allowed before generating
+func validateRoadmapOptions(c *gin.Context, opts services.RoadmapOptions) bool {
+	_, err := services.ResolvePrompt(opts)
+	if err == nil && Roadmaps != nil {
+		_, err = Roadmaps.GenerationParams(opts)
+	}
+	if err != nil {
+		status, code := roadmapErrorStatus(err)
+		c.JSON(status, generationError(opts.Lang, code, err))
+		return false
+	}
+	return true
+}
+
+// roadmapLanguage returns the requested language, or the best match for Accept-Language when lang is empty.
+// It responds with 400 when the requested language is not available.
+func roadmapLanguage(c *gin.Context, lang string) (string, bool) {
+	negotiated := services.NegotiateLanguage(c.GetHeader("Accept-Language"))
+	if lang == "" {
+		return negotiated, true
+	}
+	if !services.SupportedLanguage(lang) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     services.Message(negotiated, "unsupported_language", lang),
+			"languages": services.Languages(),
+		})
+		return negotiated, false
+	}
+	return lang, true
+}
+
+// roadmapError responds with a message localized to lang
+func roadmapError(c *gin.Context, status int, lang, key string, args ...interface{}) {
+	c.JSON(status, gin.H{"error": services.Message(lang, key, args...)})
+}
+
+// generationError is the body of a failed roadmap generation. detail carries the underlying error.
+func generationError(lang, code string, err error) gin.H {
+	return gin.H{"error": services.Message(lang, code), "code": code, "detail": err.Error()}
+}
+
+// roadmapResult builds the roadmap response. The markdown rendering is omitted with markdown=false.
+func roadmapResult(c *gin.Context, job *models.JobPosting, questionType string, roadmap *models.Roadmap, cached bool) (gin.H, error) {
+	plan, err := services.DecodeRoadmap(roadmap)
+	if err != nil {
+		return nil, err
+	}
+
+	result := gin.H{
 		"job_title":     job.Title,
 		"location":      job.Location,
-		"roadmap":       roadmap.Roadmap,
 		"question_type": questionType,
-	})
+		"lang":          roadmap.Lang,
+		"title":         plan.Title,
+		"summary":       plan.Summary,
+		"timeline":      plan.Timeline,
+		"skills":        plan.Skills,
+		"resources":     plan.Resources,
+		"total_weeks":   plan.TotalWeeks(),
+		"model":         roadmap.Model,
+		"parameters": gin.H{
+			"temperature": roadmap.Temperature,
+			"max_tokens":  roadmap.MaxTokens,
+			"top_p":       roadmap.TopP,
+		},
+		"template_id":    roadmap.TemplateID,
+		"prompt_version": roadmap.PromptVersion,
+		"cached":         cached,
+		"generated_at":   roadmap.CreatedAt,
+	}
+	if roadmap.ProfileHash != "" {
+		result["skill_gaps"] = plan.SkillGaps
+	}
+	if c.Query("markdown") != "false" {
+		result["roadmap"] = plan.Roadmap
+	}
+	return result, nil
+}
+
+// GenerateRoadmapStream streams the roadmap as Server-Sent Events.
+// "token" events carry the raw model output as it arrives and a final "done" event carries
+// the validated roadmap in the same form as GenerateRoadmap. A cached roadmap is sent as a single token unless refresh=true.
+func GenerateRoadmapStream(c *gin.Context) {
+	refresh := c.Query("refresh") == "true"
+
+	opts, ok := roadmapOptions(c)
+	if !ok {
+		return
+	}
+
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		roadmapError(c, http.StatusBadRequest, opts.Lang, "invalid_uuid")
+		return
+	}
+
+	var job models.JobPosting
+	if err := DB.Preload("Positions").Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
+		roadmapError(c, http.StatusNotFound, opts.Lang, "job_not_found")
+		return
+	}
+	// プロンプトで強調する専門分野は職種の上位のカテゴリからも決まる
+	if err := models.LoadPositionParents(DB, job.Positions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if Roadmaps == nil {
+		roadmapError(c, http.StatusInternalServerError, opts.Lang, "service_not_configured")
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx のバッファリングを無効にする
+
+	sendToken := func(token string) error {
+		c.SSEvent("token", gin.H{"content": token})
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	}
+	sendError := func(err error) {
+		_, code := roadmapErrorStatus(err)
+		c.SSEvent("error", generationError(opts.Lang, code, err))
+		c.Writer.Flush()
+	}
+	sendDone := func(roadmap *models.Roadmap, cached bool) {
+		result, err := roadmapResult(c, &job, opts.QuestionType, roadmap, cached)
+		if err != nil {
+			sendError(err)
+			return
+		}
+		c.SSEvent("done", result)
+		c.Writer.Flush()
+	}
+
+	if !refresh {
+		cached, err := services.FindCachedRoadmap(DB, Roadmaps, &job, opts)
+		if err != nil {
+			sendError(err)
+			return
+		}
+		if cached != nil {
+			if sendToken(cached.Roadmap) == nil {
+				sendDone(cached, true)
+			}
+			return
+		}
+	}
+
+	// クライアントが切断するとコンテキストがキャンセルされ、LLMへのリクエストも中断される
+	response, err := Roadmaps.StreamCareerRoadmap(c.Request.Context(), &job, opts, sendToken)
+	if errors.Is(err, services.ErrLLMBudgetExceeded) && refresh {
+		// 予算を超えた場合は再生成せずキャッシュを返す
+		if cached, findErr := services.FindCachedRoadmap(DB, Roadmaps, &job, opts); findErr == nil && cached != nil {
+			if sendToken(cached.Roadmap) == nil {
+				sendDone(cached, true)
+			}
+			return
+		}
+	}
+	if err != nil {
+		if c.Request.Context().Err() == nil {
+			sendError(err)
+		}
+		return
+	}
+
+	roadmap, err := services.SaveRoadmap(DB, Roadmaps, &job, opts, response)
+	if err != nil {
+		sendError(err)
+		return
+	}
+	sendDone(roadmap, false)
+}
+
+// roadmapErrorStatus maps a roadmap generation error to an HTTP status and an error code
+func roadmapErrorStatus(err error) (int, string) {
+	code := services.RoadmapErrorCode(err)
+	switch code {
+	case services.ErrorCodeUnknownTemplate, services.ErrorCodeModelNotAllowed:
+		return http.StatusBadRequest, code
+	case services.ErrorCodeLLMTimeout:
+		return http.StatusGatewayTimeout, code
+	case services.ErrorCodeLLMRateLimited, services.ErrorCodeLLMUnavailable, services.ErrorCodeBudgetExceeded:
+		return http.StatusServiceUnavailable, code
+	case services.ErrorCodeLLMInvalidResponse:
+		return http.StatusBadGateway, code
+	default:
+		return http.StatusInternalServerError, code
+	}
+}
+
+// invalidateRoadmaps deletes the cached roadmaps of a job whose content has changed since they were generated
+func invalidateRoadmaps(db *gorm.DB, jobID uint) error {
+	var job models.JobPosting
+	if err := db.Preload("Positions").First(&job, jobID).Error; err != nil {
+		return err
+	}
+	return services.InvalidateStaleRoadmaps(db, &job)
 }
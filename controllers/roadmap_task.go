@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"howtv-server/models"
+	"howtv-server/services"
+)
+
+// roadmapTaskInput is the optional body of CreateRoadmapTask
+type roadmapTaskInput struct {
+	QuestionType string `json:"question_type"`
+	Lang         string `json:"lang"`
+	TemplateID   string `json:"template_id"`
+	Seed         *int64 `json:"seed"`
+	Model        string `json:"model"`
+	Refresh      bool   `json:"refresh"`
+	CallbackURL  string `json:"callback_url" binding:"omitempty,url"`
+}
+
+// CreateRoadmapTask queues roadmap generation for a job and returns the task (202 Accepted).
+// The result is available from GetRoadmapTask and is posted to callback_url when given.
+func CreateRoadmapTask(c *gin.Context) {
+	input := roadmapTaskInput{QuestionType: "general"}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.QuestionType == "" {
+		input.QuestionType = "general"
+	}
+
+	// 言語は生成時ではなく受付時のリクエストで決める
+	lang, ok := roadmapLanguage(c, input.Lang)
+	if !ok {
+		return
+	}
+	opts := services.RoadmapOptions{QuestionType: input.QuestionType, Lang: lang, TemplateID: input.TemplateID, Seed: input.Seed, Model: input.Model}
+	if !validateRoadmapOptions(c, opts) {
+		return
+	}
+
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		roadmapError(c, http.StatusBadRequest, lang, "invalid_uuid")
+		return
+	}
+
+	if input.CallbackURL != "" {
+		if u, err := url.Parse(input.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url は http または https のURLを指定してください"})
+			return
+		} else if err := services.ValidateCallbackHost(c.Request.Context(), u.Hostname()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var job models.JobPosting
+	if err := DB.Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
+		roadmapError(c, http.StatusNotFound, lang, "job_not_found")
+		return
+	}
+
+	if RoadmapTasks == nil {
+		roadmapError(c, http.StatusInternalServerError, lang, "service_not_configured")
+		return
+	}
+
+	task, err := RoadmapTasks.Submit(&job, opts, input.Refresh, input.CallbackURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Location", "/api/v1/roadmap-tasks/"+task.ID.String())
+	c.JSON(http.StatusAccepted, task)
+}
+
+// GetRoadmapTask returns the status of a roadmap task, with the roadmap once it has succeeded
+func GetRoadmapTask(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無効なUUID形式です"})
+		return
+	}
+
+	var task models.RoadmapTask
+	if err := DB.First(&task, "id = ?", taskID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "タスクが見つかりませんでした"})
+		return
+	}
+
+	if task.Status != models.RoadmapTaskSucceeded {
+		c.JSON(http.StatusOK, gin.H{"task": task})
+		return
+	}
+
+	// 結果はタスクに保存したコピーを返す。キャッシュの行は求人の更新や再生成で変わるため使わない
+	roadmap, err := task.ResultRoadmap()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if roadmap == nil {
+		// 結果のコピーを保存する前のタスクはキャッシュが残っている場合のみ返す
+		var cached models.Roadmap
+		if task.RoadmapID == nil || DB.First(&cached, *task.RoadmapID).Error != nil {
+			c.JSON(http.StatusOK, gin.H{"task": task})
+			return
+		}
+		roadmap = &cached
+	}
+
+	var job models.JobPosting
+	if err := DB.Unscoped().First(&job, task.JobPostingID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := roadmapResult(c, &job, task.QuestionType, roadmap, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"task": task, "result": result})
+}
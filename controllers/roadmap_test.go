@@ -0,0 +1,423 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"howtv-server/models"
+	"howtv-server/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingGenerator は呼び出し回数を数えるテスト用のロードマップ生成サービスです
+type countingGenerator struct {
+	calls int
+}
+
+func (g *countingGenerator) GenerationParams(opts services.RoadmapOptions) (services.GenerationParams, error) {
+	return services.GenerationParams{Model: "test-model", Temperature: 0.5}, nil
+}
+
+func (g *countingGenerator) GenerateCareerRoadmap(ctx context.Context, job *models.JobPosting, opts services.RoadmapOptions) (*services.RoadmapResponse, error) {
+	g.calls++
+	return &services.RoadmapResponse{Roadmap: "# " + job.Title + "のロードマップ"}, nil
+}
+
+func (g *countingGenerator) StreamCareerRoadmap(ctx context.Context, job *models.JobPosting, opts services.RoadmapOptions, onToken func(string) error) (*services.RoadmapResponse, error) {
+	response, _ := g.GenerateCareerRoadmap(ctx, job, opts)
+	for _, token := range []string{"# " + job.Title, "のロードマップ"} {
+		if err := onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// TestGenerateRoadmapCache はロードマップのキャッシュと無効化をテストします
+func TestGenerateRoadmapCache(t *testing.T) {
+	r, db := setupTestRouter()
+	generator := &countingGenerator{}
+	Roadmaps = generator
+	defer func() { Roadmaps = nil }()
+
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+	r.PUT("/api/v1/jobs/:uuid", UpdateJobPosting)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap"
+
+	var response struct {
+		Roadmap    string `json:"roadmap"`
+		Cached     bool   `json:"cached"`
+		TemplateID string `json:"template_id"`
+	}
+	get := func(query string) {
+		w := performJSONRequest(r, "GET", path+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &response)
+	}
+
+	// 1回目は生成し、2回目はキャッシュを返す
+	get("")
+	assert.False(t, response.Cached)
+	assert.Equal(t, "general/default", response.TemplateID)
+	get("")
+	assert.True(t, response.Cached)
+	assert.Equal(t, 1, generator.calls)
+
+	// テンプレートが異なる場合は別に生成し、使ったテンプレートを記録する
+	get("?template=general/guidance")
+	assert.False(t, response.Cached)
+	assert.Equal(t, "general/guidance", response.TemplateID)
+	get("?template=guidance")
+	assert.True(t, response.Cached)
+	assert.Equal(t, 2, generator.calls)
+
+	// 存在しないテンプレートや不正なseedは400
+	w := performJSONRequest(r, "GET", path+"?template=unknown", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performJSONRequest(r, "GET", path+"?seed=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 質問タイプが異なる場合は別に生成する
+	get("?question_type=skills")
+	assert.False(t, response.Cached)
+	assert.Equal(t, 3, generator.calls)
+
+	// refresh=true ではキャッシュを使わない
+	get("?refresh=true")
+	assert.False(t, response.Cached)
+	assert.Equal(t, 4, generator.calls)
+
+	// プロンプトに関係しない項目の更新ではキャッシュは残る
+	w = performJSONRequest(r, "PUT", "/api/v1/jobs/"+job.UUID.String(), map[string]string{"salary_range": "700万〜900万円"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.Roadmap{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	// タイトルを変更するとキャッシュは破棄される
+	w = performJSONRequest(r, "PUT", "/api/v1/jobs/"+job.UUID.String(), map[string]string{"title": "シニアエンジニア"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Roadmap{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	get("")
+	assert.False(t, response.Cached)
+	assert.Equal(t, "# シニアエンジニアのロードマップ", response.Roadmap)
+	assert.Equal(t, 5, generator.calls)
+}
+
+// TestGenerateRoadmapStream はSSEでのロードマップ配信をテストします
+func TestGenerateRoadmapStream(t *testing.T) {
+	r, db := setupTestRouter()
+	generator := &countingGenerator{}
+	Roadmaps = generator
+	defer func() { Roadmaps = nil }()
+
+	r.GET("/api/v1/jobs/:uuid/roadmap/stream", GenerateRoadmapStream)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap/stream"
+
+	w := performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "event:token\ndata:{\"content\":\"# テスト求人\"}")
+	assert.Contains(t, body, "event:token\ndata:{\"content\":\"のロードマップ\"}")
+	assert.Contains(t, body, "event:done\n")
+	assert.Contains(t, body, "\"cached\":false")
+	assert.Less(t, strings.Index(body, "event:token"), strings.Index(body, "event:done"))
+
+	// 生成結果はキャッシュされ、2回目はまとめて1つのトークンで返される
+	w = performJSONRequest(r, "GET", path, nil)
+	body = w.Body.String()
+	assert.Equal(t, 1, generator.calls)
+	assert.Equal(t, 1, strings.Count(body, "event:token"))
+	assert.Contains(t, body, "\"cached\":true")
+}
+
+// failingGenerator は常に指定したエラーを返すテスト用のロードマップ生成サービスです
+type failingGenerator struct {
+	err error
+}
+
+func (g *failingGenerator) GenerationParams(opts services.RoadmapOptions) (services.GenerationParams, error) {
+	return services.GenerationParams{Model: "failing-model"}, nil
+}
+
+func (g *failingGenerator) GenerateCareerRoadmap(ctx context.Context, job *models.JobPosting, opts services.RoadmapOptions) (*services.RoadmapResponse, error) {
+	return nil, g.err
+}
+
+func (g *failingGenerator) StreamCareerRoadmap(ctx context.Context, job *models.JobPosting, opts services.RoadmapOptions, onToken func(string) error) (*services.RoadmapResponse, error) {
+	return nil, g.err
+}
+
+// TestGenerateRoadmapErrors は上流の失敗が種類ごとのステータスになることをテストします
+func TestGenerateRoadmapErrors(t *testing.T) {
+	r, db := setupTestRouter()
+	defer func() { Roadmaps = nil }()
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+
+	testCases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: deadline", services.ErrLLMTimeout), http.StatusGatewayTimeout, "llm_timeout"},
+		{fmt.Errorf("%w: 429", services.ErrLLMRateLimited), http.StatusServiceUnavailable, "llm_rate_limited"},
+		{fmt.Errorf("%w: 503", services.ErrLLMUnavailable), http.StatusServiceUnavailable, "llm_unavailable"},
+		{&services.RoadmapValidationError{Problems: []string{"skills が空です"}}, http.StatusBadGateway, "llm_invalid_response"},
+	}
+
+	for _, tc := range testCases {
+		Roadmaps = &failingGenerator{err: tc.err}
+		w := performJSONRequest(r, "GET", "/api/v1/jobs/"+job.UUID.String()+"/roadmap", nil)
+		assert.Equal(t, tc.status, w.Code)
+
+		var response struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, tc.code, response.Code)
+	}
+}
+
+// TestGenerateRoadmapLanguage は言語の指定とエラーメッセージの翻訳をテストします
+func TestGenerateRoadmapLanguage(t *testing.T) {
+	r, db := setupTestRouter()
+	Roadmaps = services.NewRoadmapService(services.NewStubProvider())
+	defer func() { Roadmaps = nil }()
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap"
+
+	get := func(path, acceptLanguage string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// 既定は日本語
+	code, response := get(path, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ja", response["lang"])
+	assert.Contains(t, response["roadmap"], "## 学習タイムライン")
+
+	// Accept-Language から英語を選び、言語ごとに別のキャッシュになる
+	code, response = get(path, "en-US,en;q=0.9")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "en", response["lang"])
+	assert.Equal(t, false, response["cached"])
+	assert.Contains(t, response["roadmap"], "## Learning timeline")
+
+	// lang パラメータは Accept-Language より優先される
+	code, response = get(path+"?lang=ja", "en")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ja", response["lang"])
+	assert.Equal(t, true, response["cached"])
+
+	// エラーメッセージも翻訳される
+	code, response = get("/api/v1/jobs/"+uuid.New().String()+"/roadmap?lang=en", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "Job posting not found", response["error"])
+	code, response = get("/api/v1/jobs/invalid/roadmap", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "無効なUUID形式です", response["error"])
+
+	// 対応していない言語は400
+	code, response = get(path+"?lang=fr", "en")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Unsupported language: fr", response["error"])
+}
+
+// TestGenerateRoadmapModel はリクエストでのモデルの指定と生成パラメーターの記録をテストします
+func TestGenerateRoadmapModel(t *testing.T) {
+	r, db := setupTestRouter()
+	temperature := float32(0.3)
+	Roadmaps = services.NewRoadmapService(services.NewStubProvider()).UseGenerationConfig(services.GenerationConfig{
+		QuestionTypes: map[string]services.GenerationSettings{"skills": {Temperature: &temperature, MaxTokens: 1000}},
+		AllowedModels: []string{"gpt-4o-mini"},
+	})
+	defer func() { Roadmaps = nil }()
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap"
+
+	var response struct {
+		Model      string `json:"model"`
+		Parameters struct {
+			Temperature float32 `json:"temperature"`
+			MaxTokens   int     `json:"max_tokens"`
+		} `json:"parameters"`
+		Code string `json:"code"`
+	}
+	w := performJSONRequest(r, "GET", path+"?question_type=skills&model=gpt-4o-mini", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "gpt-4o-mini", response.Model)
+	assert.Equal(t, float32(0.3), response.Parameters.Temperature)
+	assert.Equal(t, 1000, response.Parameters.MaxTokens)
+
+	// 許可されていないモデルは400
+	w = performJSONRequest(r, "GET", path+"?model=gpt-4", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, services.ErrorCodeModelNotAllowed, response.Code)
+}
+
+// TestGeneratePersonalizedRoadmap はプロフィールを指定したロードマップ生成をテストします
+func TestGeneratePersonalizedRoadmap(t *testing.T) {
+	r, db := setupTestRouter()
+	Roadmaps = services.NewRoadmapService(services.NewStubProvider())
+	defer func() { Roadmaps = nil }()
+	r.GET("/api/v1/jobs/:uuid/roadmap", GenerateRoadmap)
+	r.POST("/api/v1/jobs/:uuid/roadmap", GeneratePersonalizedRoadmap)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+	path := "/api/v1/jobs/" + job.UUID.String() + "/roadmap"
+	profile := map[string]interface{}{
+		"skills":              []map[string]string{{"name": "JavaScript", "level": "advanced"}},
+		"years_of_experience": 3,
+		"weekly_study_hours":  10,
+		"target_date":         time.Now().AddDate(0, 6, 0).Format("2006-01-02"),
+	}
+
+	var response struct {
+		Cached    bool                       `json:"cached"`
+		Roadmap   string                     `json:"roadmap"`
+		SkillGaps []services.RoadmapSkillGap `json:"skill_gaps"`
+	}
+	w := performJSONRequest(r, "POST", path, profile)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.False(t, response.Cached)
+	assert.NotEmpty(t, response.SkillGaps)
+	assert.Contains(t, response.Roadmap, "## スキルギャップ")
+
+	// 同じプロフィールはキャッシュされ、プロフィールなしのロードマップとは別になる
+	w = performJSONRequest(r, "POST", path, profile)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.True(t, response.Cached)
+
+	response.SkillGaps = nil
+	w = performJSONRequest(r, "GET", path, nil)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.False(t, response.Cached)
+	assert.Empty(t, response.SkillGaps)
+
+	// 不正なプロフィールは400
+	w = performJSONRequest(r, "POST", path, map[string]interface{}{
+		"skills": []map[string]string{{"name": "Go", "level": "guru"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "応募者のプロフィールが不正です")
+}
+
+// TestRoadmapTasks は非同期のロードマップ生成タスクの作成と取得をテストします
+func TestRoadmapTasks(t *testing.T) {
+	r, db := setupTestRouter()
+	// ワーカーと同じインメモリDBを使うため1接続に制限する
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	Roadmaps = services.NewRoadmapService(services.NewStubProvider())
+	RoadmapTasks = services.NewRoadmapWorkerPool(db, Roadmaps, 1)
+	defer func() { Roadmaps, RoadmapTasks = nil, nil }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	RoadmapTasks.Start(ctx)
+
+	r.POST("/api/v1/jobs/:uuid/roadmaps", CreateRoadmapTask)
+	r.GET("/api/v1/roadmap-tasks/:id", GetRoadmapTask)
+
+	job, err := createTestData(db)
+	if err != nil {
+		t.Fatalf("テストデータの作成に失敗しました: %v", err)
+	}
+
+	// 不正なコールバックURLは受け付けない
+	w := performJSONRequest(r, "POST", "/api/v1/jobs/"+job.UUID.String()+"/roadmaps", map[string]string{"callback_url": "ftp://example.com"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 内部ネットワークのアドレスへのコールバックは受け付けない
+	for _, callbackURL := range []string{"http://127.0.0.1:8080/callback", "http://[::1]/callback", "http://169.254.169.254/latest", "https://10.0.0.1/", "http://0.0.0.0/"} {
+		w = performJSONRequest(r, "POST", "/api/v1/jobs/"+job.UUID.String()+"/roadmaps", map[string]string{"callback_url": callbackURL})
+		assert.Equal(t, http.StatusBadRequest, w.Code, callbackURL)
+	}
+
+	// ボディなしでも作成できる
+	w = performJSONRequest(r, "POST", "/api/v1/jobs/"+job.UUID.String()+"/roadmaps", nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var task models.RoadmapTask
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, "general", task.QuestionType)
+	assert.Equal(t, "/api/v1/roadmap-tasks/"+task.ID.String(), w.Header().Get("Location"))
+
+	var response struct {
+		Task   models.RoadmapTask `json:"task"`
+		Result struct {
+			Title  string                  `json:"title"`
+			Skills []services.RoadmapSkill `json:"skills"`
+		} `json:"result"`
+	}
+	for i := 0; i < 500 && response.Task.Status != models.RoadmapTaskSucceeded; i++ {
+		time.Sleep(10 * time.Millisecond)
+		w = performJSONRequest(r, "GET", "/api/v1/roadmap-tasks/"+task.ID.String(), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &response)
+	}
+	assert.Equal(t, models.RoadmapTaskSucceeded, response.Task.Status)
+	assert.Equal(t, "テスト求人のロードマップ", response.Result.Title)
+	assert.NotEmpty(t, response.Result.Skills)
+
+	// キャッシュが破棄・再生成されても、タスクは生成したときの結果を返す
+	db.Where("1 = 1").Delete(&models.Roadmap{})
+	db.Model(&models.Roadmap{}).Create(&models.Roadmap{JobPostingID: job.ID, CacheKey: "other", Roadmap: "別の生成結果"})
+	response.Result.Title = ""
+	w = performJSONRequest(r, "GET", "/api/v1/roadmap-tasks/"+task.ID.String(), nil)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "テスト求人のロードマップ", response.Result.Title)
+
+	w = performJSONRequest(r, "GET", "/api/v1/roadmap-tasks/"+job.UUID.String(), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
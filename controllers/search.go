@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+
+	"howtv-server/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// FTS5の関数に渡すハイライト用のマーカー。HTMLエスケープ後に<mark>タグへ置き換える
+	highlightOpen  = "\x02"
+	highlightClose = "\x03"
+
+	snippetTokens = 24
+	snippetRunes  = 80
+
+	// trigramトークナイザは3文字未満の語を検索できない
+	minFullTextTermRunes = 3
+)
+
+// searchSorts lists the sort keys of the search endpoint; results are always ordered by relevance
+var searchSorts = map[string]sortSpec{
+	"relevance": {},
+}
+
+// searchHit is a matched row before the job postings are loaded
+type searchHit struct {
+	ID           uint
+	Score        float64
+	Title        string
+	Description  string
+	Requirements string
+}
+
+type searchHighlights struct {
+	Title        string `json:"title"`
+	Description  string `json:"description,omitempty"`
+	Requirements string `json:"requirements,omitempty"`
+}
+
+// searchResult is one job posting returned by SearchJobPostings
+type searchResult struct {
+	Job        models.JobPosting `json:"job"`
+	Score      float64           `json:"score"`
+	Highlights searchHighlights  `json:"highlights"`
+}
+
+type searchResponse struct {
+	pageResponse
+	Mode string `json:"mode"`
+}
+
+// SearchJobPostings searches job titles, descriptions and requirements ranked by relevance.
+// The FTS5 index is used when available; otherwise, or for terms shorter than three characters,
+// a LIKE scan with a simple field-weighted score is used. The structured filters of the job list
+// can be combined with q.
+func SearchJobPostings(c *gin.Context) {
+	terms, err := searchTerms(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	params, err := parsePageParams(c, searchSorts, "relevance")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Cursor != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor is not supported for search; use page"})
+		return
+	}
+
+	query, err := applyJobFilters(c, DB.Model(&models.JobPosting{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var hits []searchHit
+	var total int64
+	mode := "like"
+	if useFullTextSearch(terms) {
+		mode = "fts5"
+		hits, total, err = fullTextSearch(query, terms, params)
+	} else {
+		hits, total, err = likeSearch(query, terms, params)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hasMore := len(hits) > params.Limit
+	if hasMore {
+		hits = hits[:params.Limit]
+	}
+
+	results, err := loadSearchResults(c, hits, terms, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, searchResponse{
+		pageResponse: pageResponse{
+			Data:  results,
+			Total: total,
+			Limit: params.Limit,
+			Page:  params.Page,
+			Links: params.links(c, nil, hasMore),
+		},
+		Mode: mode,
+	})
+}
+
+// useFullTextSearch reports whether the FTS5 index can answer the given terms
+func useFullTextSearch(terms []string) bool {
+	for _, term := range terms {
+		if len([]rune(term)) < minFullTextTermRunes {
+			return false
+		}
+	}
+	return models.JobSearchAvailable(DB)
+}
+
+// ftsMatchExpression quotes every term as an FTS5 string so that user input is never parsed as query syntax
+func ftsMatchExpression(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " AND ")
+}
+
+func fullTextSearch(query *gorm.DB, terms []string, params pageParams) ([]searchHit, int64, error) {
+	table := models.JobSearchTable
+	query = query.
+		Joins(fmt.Sprintf("JOIN %s ON %s.rowid = job_postings.id", table, table)).
+		Where(table+" MATCH ?", ftsMatchExpression(terms)).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// bm25は小さいほど関連度が高いので符号を反転する。タイトルの一致を最も重く評価する
+	selectSQL := fmt.Sprintf(`job_postings.id AS id,
+		-bm25(%[1]s, 10.0, 5.0, 2.0) AS score,
+		highlight(%[1]s, 0, ?, ?) AS title,
+		snippet(%[1]s, 1, ?, ?, '…', %[2]d) AS description,
+		snippet(%[1]s, 2, ?, ?, '…', %[2]d) AS requirements`, table, snippetTokens)
+
+	var hits []searchHit
+	err := query.
+		Select(selectSQL,
+			highlightOpen, highlightClose,
+			highlightOpen, highlightClose,
+			highlightOpen, highlightClose).
+		Order("score DESC").Order("job_postings.id").
+		Offset((params.Page - 1) * params.Limit).Limit(params.Limit + 1).
+		Scan(&hits).Error
+	return hits, total, err
+}
+
+func likeSearch(query *gorm.DB, terms []string, params pageParams) ([]searchHit, int64, error) {
+	query = applyTextFilter(query, terms).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 一致したフィールドに応じた簡易スコア（タイトル3・説明2・要件1）
+	var scoreParts []string
+	var args []interface{}
+	for _, term := range terms {
+		pattern := likePattern(term)
+		scoreParts = append(scoreParts,
+			"(CASE WHEN LOWER(job_postings.title) LIKE ? ESCAPE '!' THEN 3 ELSE 0 END)",
+			"(CASE WHEN LOWER(job_postings.description) LIKE ? ESCAPE '!' THEN 2 ELSE 0 END)",
+			"(CASE WHEN LOWER(job_postings.requirements) LIKE ? ESCAPE '!' THEN 1 ELSE 0 END)",
+		)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	var hits []searchHit
+	err := query.
+		Select("job_postings.id AS id, job_postings.title AS title, job_postings.description AS description, job_postings.requirements AS requirements, "+
+			strings.Join(scoreParts, " + ")+" AS score", args...).
+		Order("score DESC").Order("job_postings.id").
+		Offset((params.Page - 1) * params.Limit).Limit(params.Limit + 1).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range hits {
+		hits[i].Title = markTerms(hits[i].Title, terms, 0)
+		hits[i].Description = markTerms(hits[i].Description, terms, snippetRunes)
+		hits[i].Requirements = markTerms(hits[i].Requirements, terms, snippetRunes)
+	}
+	return hits, total, nil
+}
+
+// loadSearchResults loads the job postings of the hits, keeping the ranking order
+func loadSearchResults(c *gin.Context, hits []searchHit, terms []string, mode string) ([]searchResult, error) {
+	results := make([]searchResult, 0, len(hits))
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	query := DB.Preload("Positions").Preload("Skills")
+	if c.Query("include_company") == "true" {
+		query = query.Preload("Company")
+	}
+
+	var jobs []models.JobPosting
+	if err := query.Where("id IN ?", ids).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	jobsByID := make(map[uint]models.JobPosting, len(jobs))
+	for _, job := range jobs {
+		jobsByID[job.ID] = job
+	}
+
+	for _, hit := range hits {
+		job, ok := jobsByID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, searchResult{
+			Job:   job,
+			Score: hit.Score,
+			Highlights: searchHighlights{
+				Title:        renderHighlight(hit.Title, true),
+				Description:  renderHighlight(hit.Description, false),
+				Requirements: renderHighlight(hit.Requirements, false),
+			},
+		})
+	}
+	return results, nil
+}
+
+// renderHighlight escapes the text as HTML and turns the markers into <mark> tags.
+// Snippets without any match are dropped unless keep is set.
+func renderHighlight(text string, keep bool) string {
+	if !keep && !strings.Contains(text, highlightOpen) {
+		return ""
+	}
+	escaped := html.EscapeString(text)
+	return strings.NewReplacer(highlightOpen, "<mark>", highlightClose, "</mark>").Replace(escaped)
+}
+
+// markTerms wraps case-insensitive occurrences of the terms in highlight markers.
+// When maxRunes is positive the text is cut to a window around the first match.
+func markTerms(text string, terms []string, maxRunes int) string {
+	runes := []rune(text)
+	// strings.ToLower はルーン単位で変換するので位置はそのまま対応する
+	lower := []rune(strings.ToLower(text))
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == string(t) {
+				spans = append(spans, span{i, i + len(t)})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		if maxRunes > 0 {
+			return ""
+		}
+		return text
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := []span{spans[0]}
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	from, to := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		from = merged[0].start - maxRunes/3
+		if from < 0 {
+			from = 0
+		}
+		to = from + maxRunes
+		if to > len(runes) {
+			to = len(runes)
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range merged {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		start, end := s.start, s.end
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+		b.WriteString(string(runes[pos:start]))
+		b.WriteString(highlightOpen + string(runes[start:end]) + highlightClose)
+		pos = end
+	}
+	b.WriteString(string(runes[pos:to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// searchTestResponse は検索APIのレスポンス形式です
+type searchTestResponse struct {
+	Data []struct {
+		Job        models.JobPosting `json:"job"`
+		Score      float64           `json:"score"`
+		Highlights struct {
+			Title        string `json:"title"`
+			Description  string `json:"description"`
+			Requirements string `json:"requirements"`
+		} `json:"highlights"`
+	} `json:"data"`
+	Total int64  `json:"total"`
+	Mode  string `json:"mode"`
+}
+
+// createSearchTestData は検索テスト用の求人を作成します
+func createSearchTestData(t *testing.T, db *gorm.DB) {
+	jobs := []models.JobPosting{
+		{Title: "バックエンドエンジニア", Description: "Go言語とPostgreSQLを使ったAPI開発を担当していただきます。", Requirements: "Kubernetesの運用経験"},
+		{Title: "Kubernetes Platform Engineer", Description: "Operate our Kubernetes clusters.", Requirements: "Terraform <3 years>"},
+		{Title: "フロントエンドエンジニア", Description: "ReactとTypeScriptで管理画面を開発します。", Requirements: "Reactの実務経験"},
+	}
+	for i := range jobs {
+		if err := db.Create(&jobs[i]).Error; err != nil {
+			t.Fatalf("テストデータの作成に失敗しました: %v", err)
+		}
+	}
+}
+
+func searchJobs(t *testing.T, r *gin.Engine, q string) searchTestResponse {
+	w := performJSONRequest(r, "GET", "/api/v1/jobs/search?q="+url.QueryEscape(q), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期待されるステータス200に対して%dが返されました: %s", w.Code, w.Body.String())
+	}
+	var response searchTestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("レスポンスのパースに失敗しました: %v", err)
+	}
+	return response
+}
+
+// TestSearchJobPostingsLike はFTS5がない場合のLIKE検索をテストします
+func TestSearchJobPostingsLike(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs/search", SearchJobPostings)
+	createSearchTestData(t, db)
+
+	// タイトルに一致する求人が上位に来る
+	response := searchJobs(t, r, "kubernetes")
+	assert.Equal(t, int64(2), response.Total)
+	assert.Equal(t, "Kubernetes Platform Engineer", response.Data[0].Job.Title)
+	assert.Equal(t, "<mark>Kubernetes</mark> Platform Engineer", response.Data[0].Highlights.Title)
+	assert.Equal(t, "<mark>Kubernetes</mark>の運用経験", response.Data[1].Highlights.Requirements)
+	assert.Empty(t, response.Data[1].Highlights.Description)
+
+	// 2文字の語は常にLIKE検索になる
+	response = searchJobs(t, r, "Go")
+	assert.Equal(t, "like", response.Mode)
+	assert.Equal(t, int64(1), response.Total)
+
+	w := performJSONRequest(r, "GET", "/api/v1/jobs/search", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestSearchJobPostingsFullText はFTS5による日本語・英語の検索をテストします。
+// FTS5は -tags sqlite_fts5 でビルドした場合のみ有効です。
+func TestSearchJobPostingsFullText(t *testing.T) {
+	r, db := setupTestRouter()
+	r.GET("/api/v1/jobs/search", SearchJobPostings)
+
+	if err := models.SetupJobSearch(db); err != nil {
+		if errors.Is(err, models.ErrFullTextUnavailable) {
+			t.Skip("FTS5が利用できないためスキップします")
+		}
+		t.Fatalf("全文検索のセットアップに失敗しました: %v", err)
+	}
+	createSearchTestData(t, db)
+
+	response := searchJobs(t, r, "管理画面")
+	assert.Equal(t, "fts5", response.Mode)
+	assert.Equal(t, int64(1), response.Total)
+	assert.Equal(t, "フロントエンドエンジニア", response.Data[0].Job.Title)
+	assert.Contains(t, response.Data[0].Highlights.Description, "<mark>管理画面</mark>")
+
+	// タイトルの一致が上位に来る
+	response = searchJobs(t, r, "kubernetes")
+	assert.Equal(t, int64(2), response.Total)
+	assert.Equal(t, "Kubernetes Platform Engineer", response.Data[0].Job.Title)
+	assert.Greater(t, response.Data[0].Score, response.Data[1].Score)
+
+	// ハイライトはHTMLエスケープされる
+	response = searchJobs(t, r, "terraform")
+	assert.Contains(t, response.Data[0].Highlights.Requirements, "<mark>Terraform</mark> &lt;3 years&gt;")
+
+	// 更新はトリガーでインデックスに反映される
+	db.Model(&models.JobPosting{}).Where("title = ?", "フロントエンドエンジニア").Update("description", "Vue.jsで開発します。")
+	assert.Equal(t, int64(0), searchJobs(t, r, "管理画面").Total)
+
+	// 論理削除された求人は検索結果に含まれない
+	db.Where("title = ?", "Kubernetes Platform Engineer").Delete(&models.JobPosting{})
+	assert.Equal(t, int64(1), searchJobs(t, r, "kubernetes").Total)
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+	"howtv-server/services"
+)
+
+// skillInput is the request body accepted by CreateSkill and UpdateSkill
+type skillInput struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Category string `json:"category" binding:"required"`
+	// 名前が一般的な単語の場合は別名だけで照合する
+	AliasesOnly bool         `json:"aliases_only"`
+	Aliases     []aliasInput `json:"aliases" binding:"dive"`
+}
+
+// aliasInput is an alias of a skill or position in a request body
+type aliasInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Lang string `json:"lang" binding:"required,oneof=en ja"`
+}
+
+// bindSkillInput reads and validates the body and writes a 400 response if it is invalid
+func bindSkillInput(c *gin.Context) (skillInput, bool) {
+	var input skillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return input, false
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if !containsCategory(input.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill category", "categories": models.SkillCategories})
+		return input, false
+	}
+	return input, true
+}
+
+func containsCategory(category string) bool {
+	for _, c := range models.SkillCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// skill builds the skill from the input. Aliases that only repeat the name or another alias are dropped.
+func (input skillInput) skill() models.Skill {
+	skill := models.Skill{Name: input.Name, Category: input.Category, AliasesOnly: input.AliasesOnly, Aliases: []models.SkillAlias{}}
+	seen := map[string]bool{models.NormalizeSkillTerm(input.Name): true}
+	for _, alias := range input.Aliases {
+		term := models.NormalizeSkillTerm(alias.Name)
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		skill.Aliases = append(skill.Aliases, models.SkillAlias{Name: strings.TrimSpace(alias.Name), Lang: alias.Lang})
+	}
+	return skill
+}
+
+// parseSkillID reads the :id path parameter and writes a 400 response if it is invalid
+func parseSkillID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// findSkill loads a skill with its aliases and writes a 404 response if it does not exist
+func findSkill(c *gin.Context, id uint) (models.Skill, bool) {
+	var skill models.Skill
+	if err := DB.Preload("Aliases").First(&skill, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+		return skill, false
+	}
+	return skill, true
+}
+
+// skillTermTaken returns the name or alias of skill that another skill already uses, or "" if there is none.
+// A term has to identify a single skill for the matcher to be unambiguous.
+func skillTermTaken(skill *models.Skill, excludeID uint) (string, error) {
+	var others []models.Skill
+	query := DB.Preload("Aliases")
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Find(&others).Error; err != nil {
+		return "", err
+	}
+
+	taken := map[string]bool{}
+	for i := range others {
+		for _, term := range others[i].Terms() {
+			taken[term] = true
+		}
+	}
+	for _, term := range skill.Terms() {
+		if taken[term] {
+			return term, nil
+		}
+	}
+	return "", nil
+}
+
+// checkSkillTerms writes a 409 response if another skill uses a name or alias of skill
+func checkSkillTerms(c *gin.Context, skill *models.Skill, excludeID uint) bool {
+	term, err := skillTermTaken(skill, excludeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if term != "" {
+		respondSkillConflict(c, term)
+		return false
+	}
+	return true
+}
+
+// respondSkillConflict writes the 409 response for a name or alias that another skill uses
+func respondSkillConflict(c *gin.Context, term string) {
+	c.JSON(http.StatusConflict, gin.H{"error": "Another skill already uses this name or alias", "term": term})
+}
+
+// reloadSkillTaxonomy makes the matcher used for prompts pick up a change of the taxonomy
+func reloadSkillTaxonomy() {
+	if err := services.LoadSkillTaxonomy(DB); err != nil {
+		log.Printf("警告: スキル分類を再読み込みできませんでした: %v", err)
+	}
+}
+
+// GetSkills returns the skill taxonomy, optionally filtered by category
+func GetSkills(c *gin.Context) {
+	query := DB.Preload("Aliases").Order("name")
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var skills []models.Skill
+	if err := query.Find(&skills).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, skills)
+}
+
+// GetSkill returns a single skill
+func GetSkill(c *gin.Context) {
+	id, ok := parseSkillID(c)
+	if !ok {
+		return
+	}
+
+	skill, ok := findSkill(c, id)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, skill)
+}
+
+// CreateSkill adds a skill to the taxonomy
+func CreateSkill(c *gin.Context) {
+	input, ok := bindSkillInput(c)
+	if !ok {
+		return
+	}
+
+	skill := input.skill()
+	if !checkSkillTerms(c, &skill, 0) {
+		return
+	}
+
+	if err := DB.Create(&skill).Error; err != nil {
+		// 事前の確認の後に同じ名前が作成された場合も一意制約の違反は409にする
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondSkillConflict(c, models.NormalizeSkillTerm(skill.Name))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	reloadSkillTaxonomy()
+
+	c.JSON(http.StatusCreated, skill)
+}
+
+// UpdateSkill replaces the name, category and aliases of a skill
+func UpdateSkill(c *gin.Context) {
+	id, ok := parseSkillID(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindSkillInput(c)
+	if !ok {
+		return
+	}
+
+	existing, ok := findSkill(c, id)
+	if !ok {
+		return
+	}
+
+	skill := input.skill()
+	skill.ID = existing.ID
+	if !checkSkillTerms(c, &skill, existing.ID) {
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existing).Select("Name", "Category", "AliasesOnly").
+			Updates(models.Skill{Name: skill.Name, Category: skill.Category, AliasesOnly: skill.AliasesOnly}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("skill_id = ?", existing.ID).Delete(&models.SkillAlias{}).Error; err != nil {
+			return err
+		}
+		for i := range skill.Aliases {
+			skill.Aliases[i].SkillID = existing.ID
+		}
+		if len(skill.Aliases) > 0 {
+			return tx.Create(&skill.Aliases).Error
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		respondSkillConflict(c, models.NormalizeSkillTerm(skill.Name))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	reloadSkillTaxonomy()
+
+	updated, ok := findSkill(c, existing.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetSkillJobs returns a page of the job postings that ask for a skill
+func GetSkillJobs(c *gin.Context) {
+	id, ok := parseSkillID(c)
+	if !ok {
+		return
+	}
+
+	skill, ok := findSkill(c, id)
+	if !ok {
+		return
+	}
+
+	respondJobPage(c, DB.Model(&models.JobPosting{}).Where("job_postings.id IN (?)",
+		DB.Table("job_skills").Select("job_posting_id").Where("skill_id = ?", skill.ID)))
+}
+
+// jobSkillsInput is the body of SetJobSkills. Either skill_ids or auto=true is required.
+type jobSkillsInput struct {
+	SkillIDs []uint `json:"skill_ids"`
+	Auto     bool   `json:"auto"`
+}
+
+// SetJobSkills sets the skills of a job posting by hand. They are kept when the posting is updated
+// until auto=true is sent, which extracts them from the posting again.
+func SetJobSkills(c *gin.Context) {
+	jobUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var input jobSkillsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.SkillIDs == nil && !input.Auto {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skill_ids or auto=true is required"})
+		return
+	}
+
+	var job models.JobPosting
+	if err := DB.Where("uuid = ?", jobUUID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job posting not found"})
+		return
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if input.Auto {
+			return services.UnlockJobSkills(tx, &job)
+		}
+		return services.SetJobSkills(tx, &job, input.SkillIDs)
+	})
+	if errors.Is(err, services.ErrUnknownSkill) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updated models.JobPosting
+	if err := DB.Preload("Positions").Preload("Skills").First(&updated, job.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Job updated but failed to retrieve it"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteSkill removes a skill and its aliases from the taxonomy
+func DeleteSkill(c *gin.Context) {
+	id, ok := parseSkillID(c)
+	if !ok {
+		return
+	}
+
+	skill, ok := findSkill(c, id)
+	if !ok {
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&skill).Association("Jobs").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("skill_id = ?", skill.ID).Delete(&models.SkillAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&skill).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	reloadSkillTaxonomy()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Skill deleted successfully"})
+}
+
+// MatchSkills returns the skills of the taxonomy found in the text query parameter, to check the taxonomy while curating it
+func MatchSkills(c *gin.Context) {
+	text := c.Query("text")
+	if strings.TrimSpace(text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	}
+
+	var skills []models.Skill
+	if err := DB.Preload("Aliases").Order("id").Find(&skills).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, services.NewSkillMatcher(skills).Match(text))
+}
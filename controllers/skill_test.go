@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+	"howtv-server/services"
+)
+
+// TestSkillCRUD はスキル分類の作成・更新・削除と重複の検出をテストします
+func TestSkillCRUD(t *testing.T) {
+	r, db := setupTestRouter()
+	// 他のテストのために既定の分類に戻す
+	defer func() {
+		skills := models.DefaultSkills()
+		db.Create(&skills)
+		services.LoadSkillTaxonomy(db)
+	}()
+	r.GET("/api/v1/skills", GetSkills)
+	r.GET("/api/v1/skills/match", MatchSkills)
+	r.GET("/api/v1/skills/:id", GetSkill)
+	r.POST("/api/v1/skills", CreateSkill)
+	r.PUT("/api/v1/skills/:id", UpdateSkill)
+	r.DELETE("/api/v1/skills/:id", DeleteSkill)
+
+	w := performJSONRequest(r, "POST", "/api/v1/skills", gin.H{
+		"name":     "Go",
+		"category": "language",
+		"aliases":  []gin.H{{"name": "golang", "lang": "en"}, {"name": "Go言語", "lang": "ja"}, {"name": "GO", "lang": "en"}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.Skill
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Len(t, created.Aliases, 2) // 名前と同じ別名は除く
+
+	// 名前や別名が他のスキルと重複する場合は409
+	w = performJSONRequest(r, "POST", "/api/v1/skills", gin.H{
+		"name": "Golang", "category": "language", "aliases": []gin.H{{"name": "ｇｏｌａｎｇ", "lang": "en"}},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "golang")
+
+	w = performJSONRequest(r, "POST", "/api/v1/skills", gin.H{"name": "Rust", "category": "unknown"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 更新は別名を置き換え、プロンプトで使う分類にも反映される
+	path := fmt.Sprintf("/api/v1/skills/%d", created.ID)
+	w = performJSONRequest(r, "PUT", path, gin.H{
+		"name": "Go", "category": "language", "aliases": []gin.H{{"name": "ゴー言語", "lang": "ja"}},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.Skill
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if assert.Len(t, updated.Aliases, 1) {
+		assert.Equal(t, "ゴー言語", updated.Aliases[0].Name)
+	}
+	assert.Equal(t, []services.SkillMatch{{Name: "Go", Category: "language", Score: 3}},
+		services.MatchJobSkills(&models.JobPosting{Title: "ゴー言語エンジニア"}))
+
+	w = performJSONRequest(r, "GET", "/api/v1/skills/match?text="+url.QueryEscape("ゴー言語とgolang"), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var matches []services.SkillMatch
+	json.Unmarshal(w.Body.Bytes(), &matches)
+	assert.Len(t, matches, 1)
+
+	w = performJSONRequest(r, "DELETE", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performJSONRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var aliasCount int64
+	db.Model(&models.SkillAlias{}).Count(&aliasCount)
+	assert.Zero(t, aliasCount)
+}
+
+// skillNames は求人のスキル名を返します
+func skillNames(job models.JobPosting) []string {
+	names := []string{}
+	for _, skill := range job.Skills {
+		names = append(names, skill.Name)
+	}
+	return names
+}
+
+// TestSkillConcurrentDuplicate は事前の確認をすり抜けた重複も409になることをテストします
+func TestSkillConcurrentDuplicate(t *testing.T) {
+	r, db := setupTestRouter()
+	// 他のテストのために既定の分類に戻す
+	defer func() {
+		skills := models.DefaultSkills()
+		db.Create(&skills)
+		services.LoadSkillTaxonomy(db)
+	}()
+	r.POST("/api/v1/skills", CreateSkill)
+	r.PUT("/api/v1/skills/:id", UpdateSkill)
+
+	w := performJSONRequest(r, "POST", "/api/v1/skills", gin.H{"name": "Elixir", "category": "language"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.Skill
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	insertBeforeWrite(t, db, "skills", "Haskell")
+	w = performJSONRequest(r, "POST", "/api/v1/skills", gin.H{"name": "Haskell", "category": "language"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already uses")
+
+	insertBeforeWrite(t, db, "skills", "OCaml")
+	w = performJSONRequest(r, "PUT", fmt.Sprintf("/api/v1/skills/%d", created.ID), gin.H{"name": "OCaml", "category": "language"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already uses")
+}
+
+// TestJobSkills は求人のスキルの自動抽出・手動編集・絞り込みをテストします
+func TestJobSkills(t *testing.T) {
+	r, db := setupTestRouter()
+	skills := models.DefaultSkills()
+	db.Create(&skills)
+	services.LoadSkillTaxonomy(db)
+	r.GET("/api/v1/jobs", GetJobPostings)
+	r.POST("/api/v1/jobs", CreateJobPosting)
+	r.PUT("/api/v1/jobs/:uuid", UpdateJobPosting)
+	r.PUT("/api/v1/jobs/:uuid/skills", SetJobSkills)
+	r.GET("/api/v1/skills/:id/jobs", GetSkillJobs)
+
+	// 作成時に求人の本文からスキルが抽出される
+	w := performJSONRequest(r, "POST", "/api/v1/jobs", gin.H{
+		"title": "Goエンジニア", "requirements": "golangとPostgreSQLの経験", "status": "公開中",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var job models.JobPosting
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.ElementsMatch(t, []string{"Go", "PostgreSQL"}, skillNames(job))
+
+	w = performJSONRequest(r, "POST", "/api/v1/jobs", gin.H{"title": "Reactエンジニア", "status": "公開中"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// 更新時に抽出し直す
+	jobPath := "/api/v1/jobs/" + job.UUID.String()
+	w = performJSONRequest(r, "PUT", jobPath, gin.H{"title": "Goエンジニア", "requirements": "golangとMySQLの経験"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.ElementsMatch(t, []string{"Go", "MySQL"}, skillNames(job))
+
+	// skill=go&skill=react はいずれかのスキルを持つ求人に絞り込む。別名でもよい
+	assert.Len(t, getJobList(t, r, "/api/v1/jobs?skill=go&skill=react").Data, 2)
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(getJobList(t, r, "/api/v1/jobs?skill=golang")))
+	w = performJSONRequest(r, "GET", "/api/v1/jobs?skill=cobol", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 手動で設定したスキルは更新しても上書きされない
+	var rust models.Skill
+	db.Where("name = ?", "Rust").First(&rust)
+	w = performJSONRequest(r, "PUT", jobPath+"/skills", gin.H{"skill_ids": []uint{rust.ID}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performJSONRequest(r, "PUT", jobPath, gin.H{"title": "Goエンジニア", "requirements": "golangの経験"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.True(t, job.SkillsLocked)
+	assert.Equal(t, []string{"Rust"}, skillNames(job))
+
+	w = performJSONRequest(r, "PUT", jobPath+"/skills", gin.H{"skill_ids": []uint{99999}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// スキルごとの求人一覧
+	w = performJSONRequest(r, "GET", fmt.Sprintf("/api/v1/skills/%d/jobs", rust.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page jobListResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, []string{"Goエンジニア"}, jobTitles(page))
+
+	// auto=true で自動抽出に戻す
+	w = performJSONRequest(r, "PUT", jobPath+"/skills", gin.H{"auto": true})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &job)
+	assert.False(t, job.SkillsLocked)
+	assert.Equal(t, []string{"Go"}, skillNames(job))
+}
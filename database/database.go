@@ -0,0 +1,49 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// ErrUnsupportedDriver is returned for a DB_DRIVER other than sqlite, postgres and mysql
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+// Dialector returns the GORM dialector of the driver. For sqlite, dsn is the path of the database file.
+func Dialector(driver, dsn string) (gorm.Dialector, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("DB_DSN is required for %s", driver)
+	}
+	switch driver {
+	case "", "sqlite":
+		return sqlite.Open(dsn), nil
+	case "postgres":
+		return postgres.Open(dsn), nil
+	case "mysql":
+		return mysql.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, driver)
+}
+
+// Open connects to the database and migrates its schema
+func Open(driver, dsn string) (*gorm.DB, error) {
+	dialector, err := Dialector(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	// 一意制約の違反をドライバーに関わらず gorm.ErrDuplicatedKey として扱う
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := models.Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"howtv-server/models"
+)
+
+// TestDialector は対応していないドライバーと接続文字列の指定漏れをテストします
+func TestDialector(t *testing.T) {
+	for _, driver := range []string{"sqlite", "postgres", "mysql"} {
+		if _, err := Dialector(driver, "dsn"); err != nil {
+			t.Errorf("%s: %v", driver, err)
+		}
+	}
+
+	if _, err := Dialector("oracle", "dsn"); !errors.Is(err, ErrUnsupportedDriver) {
+		t.Errorf("期待されるErrUnsupportedDriverに対して %v が返されました", err)
+	}
+	if _, err := Dialector("postgres", ""); err == nil {
+		t.Error("DB_DSNがない場合はエラーになるべきです")
+	}
+}
+
+// TestOpen はSQLiteに接続してスキーマが作成されることをテストします
+func TestOpen(t *testing.T) {
+	db, err := Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("データベースを開けません: %v", err)
+	}
+
+	job := models.JobPosting{Title: "UUIDの保存"}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("求人の作成に失敗しました: %v", err)
+	}
+	var found models.JobPosting
+	if err := db.Where("uuid = ?", job.UUID).First(&found).Error; err != nil || found.ID != job.ID {
+		t.Errorf("UUIDで求人を取得できません: %v", err)
+	}
+}
@@ -1,19 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 
 	"howtv-server/config"
 	"howtv-server/controllers"
-	"howtv-server/models"
-	"howtv-server/scripts"
+	"howtv-server/database"
+	"howtv-server/services"
 )
 
 func setupRouter() *gin.Engine {
@@ -35,45 +34,157 @@ func setupRouter() *gin.Engine {
 	{
 		// Job Postings
 		v1.GET("/jobs", controllers.GetJobPostings)
+		v1.GET("/jobs/search", controllers.SearchJobPostings)
 		v1.GET("/jobs/:uuid", controllers.GetJobPosting)
 		v1.POST("/jobs", controllers.CreateJobPosting)
 		v1.PUT("/jobs/:uuid", controllers.UpdateJobPosting)
 		v1.DELETE("/jobs/:uuid", controllers.DeleteJobPosting)
 
+		// Job status lifecycle
+		v1.GET("/job-statuses", controllers.GetJobStatuses)
+		v1.POST("/jobs/:uuid/publish", controllers.PublishJobPosting)
+		v1.POST("/jobs/:uuid/pause", controllers.PauseJobPosting)
+		v1.POST("/jobs/:uuid/close", controllers.CloseJobPosting)
+		v1.GET("/jobs/:uuid/status-history", controllers.GetJobStatusHistory)
+
+		// Companies
+		v1.GET("/companies", controllers.GetCompanies)
+		v1.GET("/companies/:id", controllers.GetCompany)
+		v1.POST("/companies", controllers.CreateCompany)
+		v1.PUT("/companies/:id", controllers.UpdateCompany)
+		v1.DELETE("/companies/:id", controllers.DeleteCompany)
+		v1.GET("/companies/:id/jobs", controllers.GetCompanyJobs)
+
 		// Positions
 		v1.GET("/positions", controllers.GetPositions)
 		v1.POST("/positions", controllers.CreatePosition)
+		v1.GET("/positions/tree", controllers.GetPositionTree)
+		v1.GET("/positions/:id", controllers.GetPosition)
+		v1.PUT("/positions/:id", controllers.UpdatePosition)
+		v1.DELETE("/positions/:id", controllers.DeletePosition)
+		v1.GET("/positions/:id/jobs", controllers.GetPositionJobs)
 		v1.POST("/jobs/:uuid/positions", controllers.AssignPositionsToJob)
 
+		// Skill taxonomy
+		v1.GET("/skills", controllers.GetSkills)
+		v1.GET("/skills/match", controllers.MatchSkills)
+		v1.GET("/skills/:id", controllers.GetSkill)
+		v1.POST("/skills", controllers.CreateSkill)
+		v1.PUT("/skills/:id", controllers.UpdateSkill)
+		v1.DELETE("/skills/:id", controllers.DeleteSkill)
+		v1.GET("/skills/:id/jobs", controllers.GetSkillJobs)
+		v1.PUT("/jobs/:uuid/skills", controllers.SetJobSkills)
+
 		// Roadmap Generation
 		v1.GET("/jobs/:uuid/roadmap", controllers.GenerateRoadmap)
+		v1.POST("/jobs/:uuid/roadmap", controllers.GeneratePersonalizedRoadmap)
+		v1.GET("/jobs/:uuid/roadmap/stream", controllers.GenerateRoadmapStream)
+		v1.POST("/jobs/:uuid/roadmaps", controllers.CreateRoadmapTask)
+		v1.GET("/roadmap-tasks/:id", controllers.GetRoadmapTask)
+
+		// Admin
+		v1.GET("/admin/llm-usage", controllers.GetLLMUsage)
 	}
 
 	return r
 }
 
-func initDatabase() {
+func initDatabase(cfg *config.Config) {
 	var err error
-	controllers.DB, err = gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	// サンプルデータは go run ./cmd/seed で投入する
+	controllers.DB, err = database.Open(cfg.DBDriver, cfg.DBDSN)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to open database: %v", err)
 	}
 
-	// Auto migrate the schema
-	controllers.DB.AutoMigrate(&models.Company{}, &models.JobPosting{}, &models.Position{})
+	// プロンプトのキーワード抽出に保存済みのスキル分類を使う
+	if err := services.LoadSkillTaxonomy(controllers.DB); err != nil {
+		log.Fatalf("Failed to load skill taxonomy: %v", err)
+	}
+}
 
-	// Seed the database with mock data
-	if err := scripts.SeedDatabase("mockdata.txt"); err != nil {
-		log.Printf("Warning: Failed to seed database: %v", err)
+// generationConfig converts the generation parameters of the config to those of the roadmap service
+func generationConfig(cfg config.GenerationConfig) services.GenerationConfig {
+	settings := func(p config.ModelParams) services.GenerationSettings {
+		return services.GenerationSettings{Model: p.Model, Temperature: p.Temperature, MaxTokens: p.MaxTokens, TopP: p.TopP}
+	}
+	generation := services.GenerationConfig{
+		Default:       settings(cfg.Default),
+		QuestionTypes: map[string]services.GenerationSettings{},
+		AllowedModels: cfg.AllowedModels,
+	}
+	for qt, params := range cfg.QuestionTypes {
+		generation.QuestionTypes[qt] = settings(params)
+	}
+	return generation
+}
+
+// usagePrices applies LLM_INPUT_PRICE and LLM_OUTPUT_PRICE to the model the provider uses,
+// which is its default model when LLM_MODEL is unset, and to the models of the question types
+func usagePrices(cfg *config.Config, provider services.LLMProvider) map[string]services.ModelPrice {
+	if cfg.LLMInputPrice <= 0 && cfg.LLMOutputPrice <= 0 {
+		return nil
+	}
+	models := cfg.Generation.Models()
+	if provider != nil {
+		models = append(models, provider.Model())
 	}
+	price := services.ModelPrice{Input: cfg.LLMInputPrice, Output: cfg.LLMOutputPrice}
+	prices := map[string]services.ModelPrice{}
+	for _, model := range models {
+		if model != "" {
+			prices[model] = price
+		}
+	}
+	return prices
 }
 
 func main() {
 	// 環境変数から設定を読み込む
-	config.LoadConfig()
+	cfg := config.LoadConfig()
 
 	// Initialize database
-	initDatabase()
+	initDatabase(cfg)
+
+	// プロンプトテンプレートをディスク上のファイルで上書きする
+	if cfg.PromptDir != "" {
+		if err := services.UsePromptDir(cfg.PromptDir); err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+	}
+
+	// ロードマップ生成に使うLLMプロバイダーを設定
+	provider, err := services.NewLLMProvider(services.LLMConfig{
+		Provider:   cfg.LLMProvider,
+		APIKey:     cfg.OpenAIAPIKey,
+		BaseURL:    cfg.LLMBaseURL,
+		Model:      cfg.LLMModel,
+		Timeout:    cfg.LLMTimeout,
+		MaxRetries: cfg.LLMMaxRetries,
+	})
+
+	// LLMの利用量を記録し、月間予算を超えたら新しい生成を止める
+	controllers.Usage = services.NewUsageTracker(controllers.DB, services.UsageConfig{
+		MonthlyBudget: cfg.LLMMonthlyBudget,
+		Prices:        usagePrices(cfg, provider),
+	})
+
+	if err != nil {
+		log.Printf("警告: ロードマップ生成は利用できません: %v", err)
+	} else {
+		controllers.Roadmaps = services.NewRoadmapService(provider).
+			UseGenerationConfig(generationConfig(cfg.Generation)).
+			TrackUsage(controllers.Usage)
+
+		// 非同期のロードマップ生成タスクを処理する
+		controllers.RoadmapTasks = services.NewRoadmapWorkerPool(controllers.DB, controllers.Roadmaps, cfg.RoadmapWorkers)
+		if err := controllers.RoadmapTasks.Start(context.Background()); err != nil {
+			log.Fatalf("Failed to start roadmap workers: %v", err)
+		}
+	}
+
+	// 募集期限切れの求人を定期的にクローズする
+	go services.RunExpiryScheduler(context.Background(), controllers.DB, cfg.JobExpiryInterval)
 
 	// Setup router
 	r := setupRouter()
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/config"
+	"howtv-server/services"
+)
+
+// TestUsagePrices は設定した価格がプロバイダーの既定のモデルと質問タイプごとのモデルに適用されることをテストします
+func TestUsagePrices(t *testing.T) {
+	// LLM_MODEL が未設定の場合はプロバイダーの既定のモデルに価格を付ける
+	cfg := &config.Config{
+		LLMInputPrice:  100,
+		LLMOutputPrice: 200,
+		Generation: config.GenerationConfig{
+			QuestionTypes: map[string]config.ModelParams{"skills": {Model: "gpt-4o"}},
+		},
+	}
+	provider, err := services.NewLLMProvider(services.LLMConfig{APIKey: "test-key"})
+	assert.NoError(t, err)
+
+	tracker := services.NewUsageTracker(nil, services.UsageConfig{Prices: usagePrices(cfg, provider)})
+	assert.InDelta(t, 300.0, tracker.EstimateCost(provider.Model(), 1_000_000, 1_000_000), 1e-9)
+	assert.InDelta(t, 300.0, tracker.EstimateCost("gpt-4o", 1_000_000, 1_000_000), 1e-9)
+	// 設定していないモデルは既定の価格表を使う
+	assert.InDelta(t, 0.75, tracker.EstimateCost("gpt-4o-mini", 1_000_000, 1_000_000), 1e-9)
+
+	// 価格を設定しない場合は上書きしない
+	assert.Nil(t, usagePrices(&config.Config{}, provider))
+}
@@ -1,23 +1,34 @@
 package models
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type JobPosting struct {
 	gorm.Model
-	UUID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	UUID           uuid.UUID  `gorm:"size:36;primaryKey" json:"uuid"` // uuid型はPostgreSQLにしかないため文字列で保存する
 	CompanyID      uint       `json:"company_id"`
 	Company        Company    `json:"company,omitempty" gorm:"foreignKey:CompanyID"` // 会社情報との関連付け
 	Title          string     `json:"title"`
 	Description    string     `json:"description"`
 	Requirements   string     `json:"requirements"`
-	SalaryRange    string     `json:"salary_range"`
+	SalaryRange    string     `json:"salary_range"`            // 表示用の給与テキスト
+	SalaryMin      int64      `json:"salary_min" gorm:"index"` // 0は指定なし
+	SalaryMax      int64      `json:"salary_max" gorm:"index"` // 0は指定なし
+	Currency       string     `json:"currency" gorm:"size:3"`  // ISO 4217 (JPY, USD...)
+	Period         string     `json:"period" gorm:"size:10"`   // year, month, hour
 	Location       string     `json:"location"`
 	EmploymentType string     `json:"employment_type"`
-	Status         string     `json:"status"`
+	Status         JobStatus  `json:"status" gorm:"size:20;index"`
+	PostingDate    *time.Time `json:"posting_date"`
+	ClosingDate    *time.Time `json:"closing_date" gorm:"index"` // 過ぎると自動でclosedになる
 	Positions      []Position `gorm:"many2many:job_positions" json:"positions"`
+	Skills         []Skill    `gorm:"many2many:job_skills" json:"skills"`
+	SkillsLocked   bool       `json:"skills_locked"` // スキルを手動で編集した場合は自動抽出で上書きしない
 }
 
 // UUID生成
@@ -27,3 +38,55 @@ func (jp *JobPosting) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// 保存前にステータスを正規化し、給与テキストから構造化された給与を補完し、日付をUTCに揃える
+func (jp *JobPosting) BeforeSave(tx *gorm.DB) error {
+	status, ok := ParseJobStatus(string(jp.Status))
+	if !ok {
+		return fmt.Errorf("invalid job status: %s", jp.Status)
+	}
+	jp.Status = status
+
+	jp.NormalizeSalary()
+	jp.NormalizeDates()
+	return nil
+}
+
+// NormalizeDates converts PostingDate and ClosingDate to UTC
+func (jp *JobPosting) NormalizeDates() {
+	jp.PostingDate = utcTime(jp.PostingDate)
+	jp.ClosingDate = utcTime(jp.ClosingDate)
+}
+
+// SQLiteでは日時を文字列で比較するため、タイムゾーンをUTCに揃えて保存する
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// HasStructuredSalary reports whether any structured salary field is set
+func (jp *JobPosting) HasStructuredSalary() bool {
+	return jp.SalaryMin != 0 || jp.SalaryMax != 0 || jp.Currency != "" || jp.Period != ""
+}
+
+// NormalizeSalary fills SalaryMin, SalaryMax, Currency and Period from SalaryRange
+// unless structured values were given explicitly. It reports whether SalaryRange was parsed.
+func (jp *JobPosting) NormalizeSalary() bool {
+	if jp.SalaryRange == "" || jp.HasStructuredSalary() {
+		return false
+	}
+
+	salary, err := ParseSalaryRange(jp.SalaryRange)
+	if err != nil {
+		return false
+	}
+
+	jp.SalaryMin = salary.Min
+	jp.SalaryMax = salary.Max
+	jp.Currency = salary.Currency
+	jp.Period = salary.Period
+	return true
+}
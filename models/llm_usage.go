@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LLMUsage records the tokens, latency and estimated cost of one LLM call made to generate a roadmap
+type LLMUsage struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	JobPostingID     uint      `gorm:"index" json:"job_posting_id"`
+	QuestionType     string    `gorm:"size:20" json:"question_type"`
+	Provider         string    `gorm:"size:30" json:"provider"`
+	Model            string    `gorm:"size:100" json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	Cost             float64   `json:"cost"` // 推定コスト（USD）
+	Streamed         bool      `json:"streamed"`
+	Failed           bool      `json:"failed"` // 中断または失敗した呼び出し（トークン数は途中までの概算）
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName は単数形のテーブル名を使う
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
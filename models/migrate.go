@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Migrate creates or updates the schema of all models and the auxiliary database objects
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Company{}, &JobPosting{}, &Position{}, &JobStatusChange{}, &Roadmap{}, &RoadmapTask{}, &LLMUsage{}, &Skill{}, &SkillAlias{}, &PositionAlias{}, &SeedFile{}); err != nil {
+		return err
+	}
+
+	if err := normalizeJobStatuses(db); err != nil {
+		return err
+	}
+
+	if err := backfillSalaries(db); err != nil {
+		return err
+	}
+
+	if err := seedDefaultSkills(db); err != nil {
+		return err
+	}
+
+	if err := seedDefaultPositions(db); err != nil {
+		return err
+	}
+
+	if err := SetupJobSearch(db); err != nil {
+		if !errors.Is(err, ErrFullTextUnavailable) {
+			return err
+		}
+		// 全文検索が使えない場合はLIKE検索で代替する
+		if db.Dialector.Name() == "sqlite" {
+			log.Printf("警告: %v (go build -tags sqlite_fts5 で有効になります)", err)
+		}
+	}
+
+	return nil
+}
+
+// backfillSalaries fills the structured salary columns of rows stored before they existed
+func backfillSalaries(db *gorm.DB) error {
+	var jobs []JobPosting
+	return db.Where("salary_range <> '' AND (currency = '' OR currency IS NULL)").
+		FindInBatches(&jobs, 200, func(tx *gorm.DB, batch int) error {
+			for i := range jobs {
+				if !jobs[i].NormalizeSalary() {
+					continue
+				}
+				if err := tx.Model(&jobs[i]).UpdateColumns(map[string]interface{}{
+					"salary_min": jobs[i].SalaryMin,
+					"salary_max": jobs[i].SalaryMax,
+					"currency":   jobs[i].Currency,
+					"period":     jobs[i].Period,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
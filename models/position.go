@@ -2,9 +2,137 @@ package models
 
 import "gorm.io/gorm"
 
+// Position is a node of the position taxonomy. Positions without a parent are top level categories
+// such as "エンジニアリング", and job postings are usually assigned the leaves such as "バックエンドエンジニア".
 type Position struct {
 	gorm.Model
-	ID   uint         `gorm:"primaryKey" json:"id"`
-	Name string       `json:"name" gorm:"uniqueIndex"`
-	Jobs []JobPosting `gorm:"many2many:job_positions" json:"jobs,omitempty"`
+	ID       uint            `gorm:"primaryKey" json:"id"`
+	Name     string          `json:"name" gorm:"size:100;uniqueIndex"`  // MySQLはサイズのないTEXTにインデックスを張れない
+	NameJa   string          `gorm:"size:100" json:"name_ja,omitempty"` // 表示名（日本語）
+	NameEn   string          `gorm:"size:100" json:"name_en,omitempty"` // 表示名（英語）
+	ParentID *uint           `gorm:"index" json:"parent_id"`
+	Parent   *Position       `gorm:"-" json:"-"` // LoadPositionParents で読み込む上位のポジション
+	Aliases  []PositionAlias `gorm:"constraint:OnDelete:CASCADE" json:"aliases,omitempty"`
+	Children []Position      `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Jobs     []JobPosting    `gorm:"many2many:job_positions" json:"jobs,omitempty"`
+}
+
+// PositionAlias is another name of a position, such as "Backend Engineer" or "サーバーサイドエンジニア"
+type PositionAlias struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	PositionID uint   `gorm:"index" json:"-"`
+	Name       string `gorm:"size:100" json:"name"`
+	Lang       string `gorm:"size:10" json:"lang"` // en, ja
+}
+
+// DisplayName returns the name of the position in lang, falling back to Name
+func (p *Position) DisplayName(lang string) string {
+	switch {
+	case lang == "ja" && p.NameJa != "":
+		return p.NameJa
+	case lang == "en" && p.NameEn != "":
+		return p.NameEn
+	}
+	return p.Name
+}
+
+// Terms returns the normalized names, display names and aliases the position can be looked up by
+func (p *Position) Terms() []string {
+	var terms []string
+	for _, name := range []string{p.Name, p.NameJa, p.NameEn} {
+		if name != "" {
+			terms = append(terms, NormalizeSkillTerm(name))
+		}
+	}
+	for _, alias := range p.Aliases {
+		terms = append(terms, NormalizeSkillTerm(alias.Name))
+	}
+	return terms
+}
+
+// PositionsByTerm indexes all positions by their normalized names, display names and aliases,
+// so that "Backend Engineer", "backend engineer" and "バックエンドエンジニア" find the same position
+func PositionsByTerm(db *gorm.DB) (map[string]Position, error) {
+	var positions []Position
+	if err := db.Preload("Aliases").Order("id").Find(&positions).Error; err != nil {
+		return nil, err
+	}
+	byTerm := map[string]Position{}
+	for _, position := range positions {
+		for _, term := range position.Terms() {
+			if _, ok := byTerm[term]; !ok {
+				byTerm[term] = position
+			}
+		}
+	}
+	return byTerm, nil
+}
+
+// LoadPositionParents sets Parent along the stored parent_id chain of each position, up to its top level category.
+// Positions already loaded are shared, and a cycle in parent_id is cut so that the chain always ends.
+func LoadPositionParents(db *gorm.DB, positions []Position) error {
+	loaded := map[uint]*Position{}
+	var pending []*Position
+	for i := range positions {
+		loaded[positions[i].ID] = &positions[i]
+		pending = append(pending, &positions[i])
+	}
+
+	for len(pending) > 0 {
+		var ids []uint
+		for _, p := range pending {
+			if p.ParentID != nil && loaded[*p.ParentID] == nil {
+				ids = append(ids, *p.ParentID)
+			}
+		}
+		var parents []Position
+		if len(ids) > 0 {
+			if err := db.Where("id IN ?", ids).Find(&parents).Error; err != nil {
+				return err
+			}
+		}
+		for i := range parents {
+			loaded[parents[i].ID] = &parents[i]
+		}
+
+		for _, p := range pending {
+			if p.ParentID == nil || loaded[*p.ParentID] == nil {
+				continue
+			}
+			// 壊れたparent_idの循環は閉じる手前で切る
+			if parent := loaded[*p.ParentID]; !parent.hasAncestor(p) {
+				p.Parent = parent
+			}
+		}
+		pending = pending[:0]
+		for i := range parents {
+			pending = append(pending, loaded[parents[i].ID])
+		}
+	}
+	return nil
+}
+
+// hasAncestor reports whether ancestor is p itself or one of its loaded parents
+func (p *Position) hasAncestor(ancestor *Position) bool {
+	for node := p; node != nil; node = node.Parent {
+		if node == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// PositionSubtreeIDs returns the IDs of the given positions and all of their descendants.
+// UNION drops rows that were already visited, so a cycle in parent_id cannot make the query run forever.
+func PositionSubtreeIDs(db *gorm.DB, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var subtree []uint
+	err := db.Raw(`WITH RECURSIVE subtree(id) AS (
+		SELECT id FROM positions WHERE id IN ? AND deleted_at IS NULL
+		UNION
+		SELECT p.id FROM positions p JOIN subtree s ON p.parent_id = s.id WHERE p.deleted_at IS NULL
+	) SELECT id FROM subtree`, ids).Scan(&subtree).Error
+	return subtree, err
 }
@@ -0,0 +1,112 @@
+package models
+
+import "gorm.io/gorm"
+
+// defaultPosition is a node of the default position taxonomy
+type defaultPosition struct {
+	name, nameEn string
+	aliases      []PositionAlias
+	children     []defaultPosition
+}
+
+func defaultPositionNode(name, nameEn string, children ...defaultPosition) defaultPosition {
+	return defaultPosition{name: name, nameEn: nameEn, children: children}
+}
+
+// withAliases adds English and Japanese aliases to the node
+func (p defaultPosition) withAliases(en []string, ja ...string) defaultPosition {
+	for _, alias := range en {
+		p.aliases = append(p.aliases, PositionAlias{Name: alias, Lang: "en"})
+	}
+	for _, alias := range ja {
+		p.aliases = append(p.aliases, PositionAlias{Name: alias, Lang: "ja"})
+	}
+	return p
+}
+
+// defaultPositionTree is the taxonomy stored until positions are curated through the API.
+// The English names are the ones used in mockdata.txt, so that its postings find their positions.
+var defaultPositionTree = []defaultPosition{
+	defaultPositionNode("エンジニアリング", "Engineering",
+		defaultPositionNode("アプリケーション開発", "Application Development",
+			defaultPositionNode("フロントエンドエンジニア", "Frontend Engineer").withAliases([]string{"Front-end Engineer", "Front End Engineer"}),
+			defaultPositionNode("バックエンドエンジニア", "Backend Engineer").withAliases([]string{"Back-end Engineer", "Server-side Engineer"}, "サーバーサイドエンジニア"),
+			defaultPositionNode("フルスタックエンジニア", "Fullstack Engineer").withAliases([]string{"Full Stack Engineer", "Full-stack Engineer"}),
+			defaultPositionNode("モバイルエンジニア", "Mobile Engineer").withAliases([]string{"iOS Engineer", "Android Engineer"}, "アプリエンジニア"),
+			defaultPositionNode("ゲームエンジニア", "Game Engineer").withAliases([]string{"Game Developer"}, "ゲームプログラマー"),
+		),
+		defaultPositionNode("インフラ・クラウド", "Infrastructure & Cloud",
+			defaultPositionNode("インフラエンジニア", "Infrastructure Engineer"),
+			defaultPositionNode("クラウドエンジニア", "Cloud Engineer"),
+			defaultPositionNode("DevOpsエンジニア", "DevOps Engineer").withAliases([]string{"SRE", "Site Reliability Engineer"}),
+			defaultPositionNode("セキュリティエンジニア", "Security Engineer"),
+			defaultPositionNode("ソリューションアーキテクト", "Solutions Architect").withAliases([]string{"Solution Architect", "Cloud Architect"}),
+		),
+		defaultPositionNode("データ・AI", "Data & AI",
+			defaultPositionNode("データエンジニア", "Data Engineer"),
+			defaultPositionNode("データベースエンジニア", "Database Engineer").withAliases([]string{"DBA", "Database Administrator"}),
+			defaultPositionNode("AI/ML エンジニア", "AI/ML Engineer").withAliases([]string{"Machine Learning Engineer", "ML Engineer", "AI Engineer"}, "機械学習エンジニア"),
+		),
+		defaultPositionNode("品質保証", "Quality Assurance",
+			defaultPositionNode("QAエンジニア", "QA Engineer").withAliases([]string{"Test Engineer"}, "テストエンジニア"),
+		),
+	),
+}
+
+// seedDefaultPositions stores the default taxonomy while the positions are still a flat list.
+// Positions that already exist under the same name are placed into the tree and keep their display names and aliases.
+func seedDefaultPositions(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&Position{}).Where("parent_id IS NOT NULL").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return seedPositionNodes(tx, defaultPositionTree, nil)
+	})
+}
+
+func seedPositionNodes(db *gorm.DB, nodes []defaultPosition, parentID *uint) error {
+	for _, node := range nodes {
+		var position Position
+		if err := db.Preload("Aliases").Where(Position{Name: node.name}).
+			Attrs(Position{NameJa: node.name, NameEn: node.nameEn, ParentID: parentID}).
+			FirstOrCreate(&position).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if position.NameJa == "" {
+			updates["name_ja"] = node.name
+		}
+		if position.NameEn == "" {
+			updates["name_en"] = node.nameEn
+		}
+		if position.ParentID == nil && parentID != nil {
+			updates["parent_id"] = *parentID
+		}
+		if len(updates) > 0 {
+			if err := db.Model(&position).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if len(position.Aliases) == 0 && len(node.aliases) > 0 {
+			aliases := make([]PositionAlias, len(node.aliases))
+			for i, alias := range node.aliases {
+				alias.PositionID = position.ID
+				aliases[i] = alias
+			}
+			if err := db.Create(&aliases).Error; err != nil {
+				return err
+			}
+		}
+
+		id := position.ID
+		if err := seedPositionNodes(db, node.children, &id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestSeedDefaultPositions は既存のフラットなポジションが分類の木に組み込まれることをテストします
+func TestSeedDefaultPositions(t *testing.T) {
+	db := setupTestDB()
+
+	// 分類を導入する前のシードを再現する
+	db.Create(&Position{Name: "バックエンドエンジニア"})
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("マイグレーションに失敗しました: %v", err)
+	}
+
+	var backend Position
+	db.Preload("Aliases").Where("name = ?", "バックエンドエンジニア").First(&backend)
+	if backend.ParentID == nil || backend.NameEn != "Backend Engineer" || len(backend.Aliases) == 0 {
+		t.Errorf("既存のポジションが分類に組み込まれていません: %+v", backend)
+	}
+	if backend.DisplayName("en") != "Backend Engineer" || backend.DisplayName("ja") != "バックエンドエンジニア" {
+		t.Errorf("表示名が正しくありません: %+v", backend)
+	}
+
+	// 2回目は何も変えない
+	var before int64
+	db.Model(&Position{}).Count(&before)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("マイグレーションに失敗しました: %v", err)
+	}
+	var after int64
+	db.Model(&Position{}).Count(&after)
+	if before != after {
+		t.Errorf("再実行でポジションが増えました: %d -> %d", before, after)
+	}
+}
+
+// TestPositionSubtreeIDs はカテゴリの配下のポジションがすべて返されることをテストします
+func TestPositionSubtreeIDs(t *testing.T) {
+	db := setupTestDB()
+
+	root := Position{Name: "エンジニアリング"}
+	db.Create(&root)
+	app := Position{Name: "アプリケーション開発", ParentID: &root.ID}
+	db.Create(&app)
+	backend := Position{Name: "バックエンドエンジニア", ParentID: &app.ID}
+	db.Create(&backend)
+	other := Position{Name: "営業"}
+	db.Create(&other)
+
+	ids, err := PositionSubtreeIDs(db, []uint{root.ID})
+	if err != nil {
+		t.Fatalf("配下のポジションの取得に失敗しました: %v", err)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	want := []uint{root.ID, app.ID, backend.ID}
+	if len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Errorf("期待される %v に対して %v が返されました", want, ids)
+	}
+
+	// 葉は自身のみ
+	ids, _ = PositionSubtreeIDs(db, []uint{backend.ID})
+	if len(ids) != 1 || ids[0] != backend.ID {
+		t.Errorf("葉のポジションで %v が返されました", ids)
+	}
+}
+
+// TestLoadPositionParents は保存された親のポジションがルートまで読み込まれることをテストします
+func TestLoadPositionParents(t *testing.T) {
+	db := setupTestDB()
+
+	root := Position{Name: "エンジニアリング"}
+	db.Create(&root)
+	app := Position{Name: "アプリケーション開発", ParentID: &root.ID}
+	db.Create(&app)
+	backend := Position{Name: "バックエンドエンジニア", ParentID: &app.ID}
+	db.Create(&backend)
+	platform := Position{Name: "Platform Engineer", ParentID: &backend.ID}
+	db.Create(&platform)
+
+	positions := []Position{platform, app}
+	if err := LoadPositionParents(db, positions); err != nil {
+		t.Fatalf("親のポジションの取得に失敗しました: %v", err)
+	}
+	var names []string
+	for node := &positions[0]; node != nil; node = node.Parent {
+		names = append(names, node.Name)
+	}
+	if len(names) != 4 || names[1] != "バックエンドエンジニア" || names[3] != "エンジニアリング" {
+		t.Errorf("親のポジションが正しくありません: %v", names)
+	}
+	if positions[1].Parent == nil || positions[1].Parent.Name != "エンジニアリング" {
+		t.Errorf("2件目の親のポジションが正しくありません: %+v", positions[1].Parent)
+	}
+
+	// parent_idが循環していても終了する
+	db.Model(&root).Update("parent_id", platform.ID)
+	positions = []Position{platform}
+	if err := LoadPositionParents(db, positions); err != nil {
+		t.Fatalf("親のポジションの取得に失敗しました: %v", err)
+	}
+	depth := 0
+	for node := &positions[0]; node != nil; node = node.Parent {
+		depth++
+	}
+	if depth != 4 {
+		t.Errorf("循環の手前で止まっていません: %d", depth)
+	}
+}
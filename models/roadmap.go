@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Roadmap is a generated career roadmap cached per job content, question type, model, prompt templates and candidate profile
+type Roadmap struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	JobPostingID  uint      `gorm:"index" json:"job_posting_id"`
+	CacheKey      string    `gorm:"size:64;uniqueIndex" json:"-"`
+	JobHash       string    `gorm:"size:64" json:"-"` // 生成時の求人内容のハッシュ（変更検知用）
+	QuestionType  string    `gorm:"size:20" json:"question_type"`
+	Lang          string    `gorm:"size:10" json:"lang"`
+	Model         string    `json:"model"`
+	Temperature   float32   `json:"temperature"` // 生成に使ったパラメーター（監査用）
+	MaxTokens     int       `json:"max_tokens"`
+	TopP          float32   `json:"top_p"`
+	TemplateID    string    `gorm:"size:100" json:"template_id"`   // 生成に使ったユーザープロンプトのテンプレート
+	PromptVersion string    `gorm:"size:20" json:"prompt_version"` // そのテンプレートのバージョン
+	ProfileHash   string    `gorm:"size:64" json:"-"`              // 応募者のプロフィールで個別化した場合のみ
+	Roadmap       string    `json:"roadmap"`                       // マークダウン
+	Plan          string    `json:"-"`                             // 構造化されたロードマップ（JSON）
+	CreatedAt     time.Time `json:"created_at"`
+}
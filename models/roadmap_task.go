@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ロードマップ生成タスクの状態
+const (
+	RoadmapTaskPending   = "pending"
+	RoadmapTaskRunning   = "running"
+	RoadmapTaskSucceeded = "succeeded"
+	RoadmapTaskFailed    = "failed"
+)
+
+// コールバックの送信結果
+const (
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// RoadmapTask is an asynchronous roadmap generation request
+type RoadmapTask struct {
+	ID             uuid.UUID  `gorm:"size:36;primaryKey" json:"id"`
+	JobPostingID   uint       `gorm:"index" json:"-"`
+	JobUUID        uuid.UUID  `gorm:"size:36" json:"job_uuid"`
+	QuestionType   string     `gorm:"size:20" json:"question_type"`
+	Lang           string     `gorm:"size:10" json:"lang"`
+	TemplateID     string     `gorm:"size:100" json:"template_id,omitempty"`
+	Seed           *int64     `json:"seed,omitempty"`
+	Model          string     `gorm:"size:100" json:"model,omitempty"` // リクエストで指定したモデル
+	Refresh        bool       `json:"refresh"`
+	Status         string     `gorm:"size:20;index" json:"status"`
+	RoadmapID      *uint      `json:"roadmap_id,omitempty"`
+	Result         string     `json:"-"` // 生成したロードマップのコピー（JSON）。キャッシュの行は破棄・再生成されるため
+	Error          string     `json:"error,omitempty"`
+	ErrorCode      string     `gorm:"size:50" json:"error_code,omitempty"`
+	CallbackURL    string     `json:"callback_url,omitempty"`
+	CallbackStatus string     `gorm:"size:20" json:"callback_status,omitempty"`
+	CallbackError  string     `json:"callback_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// UUID生成
+func (t *RoadmapTask) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	if t.Status == "" {
+		t.Status = RoadmapTaskPending
+	}
+	return nil
+}
+
+// roadmapSnapshot has the fields of Roadmap, including those hidden from its JSON
+type roadmapSnapshot struct {
+	ID            uint      `json:"id"`
+	JobPostingID  uint      `json:"job_posting_id"`
+	CacheKey      string    `json:"cache_key"`
+	JobHash       string    `json:"job_hash"`
+	QuestionType  string    `json:"question_type"`
+	Lang          string    `json:"lang"`
+	Model         string    `json:"model"`
+	Temperature   float32   `json:"temperature"`
+	MaxTokens     int       `json:"max_tokens"`
+	TopP          float32   `json:"top_p"`
+	TemplateID    string    `json:"template_id"`
+	PromptVersion string    `json:"prompt_version"`
+	ProfileHash   string    `json:"profile_hash"`
+	Roadmap       string    `json:"roadmap"`
+	Plan          string    `json:"plan"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SetResult stores a copy of the generated roadmap on the task
+func (t *RoadmapTask) SetResult(roadmap *Roadmap) error {
+	data, err := json.Marshal(roadmapSnapshot(*roadmap))
+	if err != nil {
+		return err
+	}
+	t.RoadmapID = &roadmap.ID
+	t.Result = string(data)
+	return nil
+}
+
+// ResultRoadmap returns the roadmap stored by SetResult, or nil for tasks without a stored result
+func (t *RoadmapTask) ResultRoadmap() (*Roadmap, error) {
+	if t.Result == "" {
+		return nil, nil
+	}
+	var snapshot roadmapSnapshot
+	if err := json.Unmarshal([]byte(t.Result), &snapshot); err != nil {
+		return nil, err
+	}
+	roadmap := Roadmap(snapshot)
+	return &roadmap, nil
+}
+
+// Finished reports whether the task has succeeded or failed
+func (t *RoadmapTask) Finished() bool {
+	return t.Status == RoadmapTaskSucceeded || t.Status == RoadmapTaskFailed
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 給与の支給単位
+const (
+	SalaryPeriodYear  = "year"
+	SalaryPeriodMonth = "month"
+	SalaryPeriodHour  = "hour"
+)
+
+// ErrUnparsableSalary is returned when no amount can be found in a salary text
+var ErrUnparsableSalary = errors.New("salary range could not be parsed")
+
+// Salary is the structured form of a free-text salary range.
+// Amounts are in whole units of Currency; 0 means the bound is not specified.
+type Salary struct {
+	Min      int64
+	Max      int64
+	Currency string
+	Period   string
+}
+
+// 数値とその直後の単位（万・千・億・M・K など）
+var salaryAmountPattern = regexp.MustCompile(`(\d+(?:,\d{3})*(?:\.\d+)?)\s*(万|千|億|(?i:million|mil|thousand)|M|K|k)?`)
+
+var salaryUnits = map[string]float64{
+	"万":        1e4,
+	"千":        1e3,
+	"億":        1e8,
+	"million":  1e6,
+	"mil":      1e6,
+	"M":        1e6,
+	"thousand": 1e3,
+	"K":        1e3,
+	"k":        1e3,
+}
+
+var salaryPeriodKeywords = []struct {
+	period   string
+	keywords []string
+}{
+	{SalaryPeriodHour, []string{"時給", "hourly", "per hour", "/hour", "/hr", "an hour"}},
+	{SalaryPeriodMonth, []string{"月給", "月収", "月額", "monthly", "per month", "/month", "/mo", "a month"}},
+	{SalaryPeriodYear, []string{"年収", "年俸", "年額", "annual", "per year", "/year", "/yr", "a year", "yearly"}},
+}
+
+var salaryCurrencyKeywords = []struct {
+	currency string
+	keywords []string
+}{
+	{"USD", []string{"$", "usd"}},
+	{"EUR", []string{"€", "eur"}},
+	{"JPY", []string{"¥", "円", "jpy", "yen"}},
+}
+
+// ParseSalaryRange converts salary texts such as "¥5M - ¥8M annually", "600万〜800万円",
+// "年収500万円以上", "月給30万円" or "時給1,500円" into a Salary.
+// The currency defaults to JPY and the period to a year when the text does not say otherwise.
+func ParseSalaryRange(text string) (Salary, error) {
+	normalized := toHalfWidth(text)
+	lower := strings.ToLower(normalized)
+
+	salary := Salary{Currency: "JPY", Period: SalaryPeriodYear}
+	for _, c := range salaryCurrencyKeywords {
+		if containsAnyKeyword(lower, c.keywords) {
+			salary.Currency = c.currency
+			break
+		}
+	}
+	for _, p := range salaryPeriodKeywords {
+		if containsAnyKeyword(lower, p.keywords) {
+			salary.Period = p.period
+			break
+		}
+	}
+
+	type amount struct {
+		value   float64
+		unit    string
+		hasUnit bool
+		end     int
+	}
+	var amounts []amount
+	for _, m := range salaryAmountPattern.FindAllStringSubmatchIndex(normalized, -1) {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(normalized[m[2]:m[3]], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		a := amount{value: value, end: m[1]}
+		if m[4] >= 0 {
+			unit := normalized[m[4]:m[5]]
+			// "5Months" のように英単語の一部になっている場合は単位として扱わない
+			if isASCIILetterUnit(unit) && m[5] < len(normalized) && isASCIILetter(normalized[m[5]]) {
+				a.end = m[4]
+			} else {
+				// "Million" も "million" と同じ単位として扱う。"M"・"K" は1文字なのでそのまま
+				if len(unit) > 1 {
+					unit = strings.ToLower(unit)
+				}
+				a.unit, a.hasUnit = unit, true
+			}
+		}
+		amounts = append(amounts, a)
+	}
+	if len(amounts) == 0 {
+		return Salary{}, ErrUnparsableSalary
+	}
+	if len(amounts) > 2 {
+		amounts = amounts[:2]
+	}
+
+	// "600〜800万円" のように単位が後ろの数値にだけ付いている場合は前の数値にも適用する
+	if len(amounts) == 2 && !amounts[0].hasUnit && amounts[1].hasUnit {
+		amounts[0].unit, amounts[0].hasUnit = amounts[1].unit, true
+	}
+
+	values := make([]int64, len(amounts))
+	for i, a := range amounts {
+		multiplier := 1.0
+		if a.hasUnit {
+			multiplier = salaryUnits[a.unit]
+		}
+		values[i] = int64(math.Round(a.value * multiplier))
+	}
+
+	if len(values) == 2 {
+		salary.Min, salary.Max = values[0], values[1]
+		if salary.Min > salary.Max {
+			salary.Min, salary.Max = salary.Max, salary.Min
+		}
+		return salary, nil
+	}
+
+	rest := strings.TrimSpace(strings.ToLower(normalized[amounts[0].end:]))
+	switch {
+	case containsAnyKeyword(rest, []string{"以上", "〜", "~", "+", "から"}) || containsAnyKeyword(lower, []string{"from ", "starting"}):
+		salary.Min = values[0]
+	case containsAnyKeyword(rest, []string{"以下", "まで"}) || containsAnyKeyword(lower, []string{"up to", "max"}):
+		salary.Max = values[0]
+	default:
+		salary.Min, salary.Max = values[0], values[0]
+	}
+	return salary, nil
+}
+
+// toHalfWidth converts full-width ASCII characters (e.g. "６００万") to their half-width form
+func toHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			// "～" は範囲記号としてそのまま "~" になる
+			return r - 0xFEE0
+		case r == '　':
+			return ' '
+		case r == '￥':
+			return '¥'
+		case r == '－' || r == '−' || r == '–' || r == '—':
+			return '-'
+		}
+		return r
+	}, s)
+}
+
+func containsAnyKeyword(s string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCIILetterUnit(unit string) bool {
+	return unit != "" && isASCIILetter(unit[len(unit)-1])
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// JobSearchTable is the FTS5 virtual table that indexes job postings
+const JobSearchTable = "job_postings_fts"
+
+// ErrFullTextUnavailable is returned when the SQLite build has no FTS5 support.
+// mattn/go-sqlite3 only enables FTS5 when built with -tags sqlite_fts5.
+var ErrFullTextUnavailable = errors.New("full-text search (FTS5) is not available in this SQLite build")
+
+// 求人のタイトル・説明・応募要件を全文検索の対象にする。
+// trigramトークナイザは単語区切りのない日本語でも部分一致で検索できる。
+var jobSearchStatements = []string{
+	`CREATE VIRTUAL TABLE ` + JobSearchTable + ` USING fts5(
+		title, description, requirements,
+		content='job_postings', content_rowid='id',
+		tokenize='trigram'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS job_postings_fts_ai AFTER INSERT ON job_postings BEGIN
+		INSERT INTO ` + JobSearchTable + `(rowid, title, description, requirements)
+		VALUES (new.id, new.title, new.description, new.requirements);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS job_postings_fts_ad AFTER DELETE ON job_postings BEGIN
+		INSERT INTO ` + JobSearchTable + `(` + JobSearchTable + `, rowid, title, description, requirements)
+		VALUES ('delete', old.id, old.title, old.description, old.requirements);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS job_postings_fts_au AFTER UPDATE ON job_postings BEGIN
+		INSERT INTO ` + JobSearchTable + `(` + JobSearchTable + `, rowid, title, description, requirements)
+		VALUES ('delete', old.id, old.title, old.description, old.requirements);
+		INSERT INTO ` + JobSearchTable + `(rowid, title, description, requirements)
+		VALUES (new.id, new.title, new.description, new.requirements);
+	END`,
+	// 既存の求人をインデックスに取り込む
+	`INSERT INTO ` + JobSearchTable + `(` + JobSearchTable + `) VALUES ('rebuild')`,
+}
+
+// SetupJobSearch creates the FTS5 index over job postings and the triggers that keep it in sync.
+// It is a no-op when the index already exists.
+func SetupJobSearch(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return ErrFullTextUnavailable
+	}
+	if JobSearchAvailable(db) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range jobSearchStatements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				if strings.Contains(err.Error(), "no such module: fts5") {
+					return ErrFullTextUnavailable
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// JobSearchAvailable reports whether the FTS5 index has been set up
+func JobSearchAvailable(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+
+	var count int64
+	db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", JobSearchTable).Scan(&count)
+	return count > 0
+}
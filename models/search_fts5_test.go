@@ -0,0 +1,26 @@
+//go:build sqlite_fts5
+
+package models
+
+import "testing"
+
+// TestJobSearchWithFTS5Tag は sqlite_fts5 タグ付きのビルドで全文検索が使えることをテストします。
+// タグを付けてもFTS5が使えない場合（CGOなしのビルドなど）はLIKE検索に切り替わってしまうため失敗させる
+func TestJobSearchWithFTS5Tag(t *testing.T) {
+	db := setupTestDB()
+
+	if err := SetupJobSearch(db); err != nil {
+		t.Fatalf("全文検索をセットアップできません: %v", err)
+	}
+	if !JobSearchAvailable(db) {
+		t.Fatal("全文検索のインデックスが作成されていません")
+	}
+
+	// 作成した求人がインデックスから検索できる
+	db.Create(&JobPosting{Title: "全文検索テスト用の求人"})
+	var count int64
+	db.Raw("SELECT COUNT(*) FROM "+JobSearchTable+" WHERE "+JobSearchTable+" MATCH ?", `"全文検索テスト"`).Scan(&count)
+	if count != 1 {
+		t.Errorf("インデックスから求人が見つかりません: %d件", count)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SeedFile records a seed file that was applied to the database, so that applying it again is skipped
+// until its content changes
+type SeedFile struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:255;uniqueIndex" json:"name"`
+	Checksum  string    `gorm:"size:64" json:"checksum"` // 内容のSHA-256
+	AppliedAt time.Time `json:"applied_at"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// スキルの分類
+const (
+	SkillCategoryLanguage    = "language"
+	SkillCategoryFramework   = "framework"
+	SkillCategoryDatabase    = "database"
+	SkillCategoryCloud       = "cloud"
+	SkillCategoryTool        = "tool"
+	SkillCategoryPractice    = "practice"
+	SkillCategoryDomain      = "domain"
+	SkillCategoryDesign      = "design"
+	SkillCategoryPlatform    = "platform"
+	SkillCategoryDataScience = "data"
+)
+
+// SkillCategories lists the valid categories in display order
+var SkillCategories = []string{
+	SkillCategoryLanguage, SkillCategoryFramework, SkillCategoryDatabase, SkillCategoryCloud, SkillCategoryTool,
+	SkillCategoryPractice, SkillCategoryDomain, SkillCategoryDesign, SkillCategoryPlatform, SkillCategoryDataScience,
+}
+
+// Skill is a curated skill of the taxonomy used to find the skills a job posting asks for
+type Skill struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Name     string `gorm:"size:100;uniqueIndex" json:"name"` // 正規名
+	Category string `gorm:"size:20;index" json:"category"`
+	// AliasesOnly keeps the name out of matching when it is an ordinary word such as "Go" or "Express";
+	// only the aliases are found in texts. The name is still used to look the skill up.
+	AliasesOnly bool         `json:"aliases_only"`
+	Aliases     []SkillAlias `gorm:"constraint:OnDelete:CASCADE" json:"aliases,omitempty"`
+	Jobs        []JobPosting `gorm:"many2many:job_skills" json:"jobs,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// SkillAlias is another name of a skill, such as "golang" or "Go言語" for Go
+type SkillAlias struct {
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	SkillID uint   `gorm:"index" json:"-"`
+	Name    string `gorm:"size:100" json:"name"`
+	Lang    string `gorm:"size:10" json:"lang"` // en, ja
+}
+
+// Terms returns the normalized name and aliases of the skill
+func (s *Skill) Terms() []string {
+	terms := []string{NormalizeSkillTerm(s.Name)}
+	for _, alias := range s.Aliases {
+		terms = append(terms, NormalizeSkillTerm(alias.Name))
+	}
+	return terms
+}
+
+// MatchTerms returns the normalized terms that identify the skill in a text
+func (s *Skill) MatchTerms() []string {
+	if !s.AliasesOnly {
+		return s.Terms()
+	}
+	terms := make([]string, 0, len(s.Aliases))
+	for _, alias := range s.Aliases {
+		terms = append(terms, NormalizeSkillTerm(alias.Name))
+	}
+	return terms
+}
+
+// NormalizeSkillTerm folds full-width characters and case so that "Ｇｏ" and "go" compare equal
+func NormalizeSkillTerm(s string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFKC.String(s)))
+}
+
+// seedDefaultSkills stores the default taxonomy when no skill has been curated yet
+func seedDefaultSkills(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&Skill{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	skills := DefaultSkills()
+	return db.Create(&skills).Error
+}
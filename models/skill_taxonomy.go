@@ -0,0 +1,138 @@
+package models
+
+// defaultSkill builds a skill of the default taxonomy with English and Japanese aliases
+func defaultSkill(name, category string, en []string, ja ...string) Skill {
+	skill := Skill{Name: name, Category: category}
+	for _, alias := range en {
+		skill.Aliases = append(skill.Aliases, SkillAlias{Name: alias, Lang: "en"})
+	}
+	for _, alias := range ja {
+		skill.Aliases = append(skill.Aliases, SkillAlias{Name: alias, Lang: "ja"})
+	}
+	return skill
+}
+
+// ambiguousSkill builds a skill whose name is an ordinary word, so that only its aliases are matched
+func ambiguousSkill(name, category string, en []string, ja ...string) Skill {
+	skill := defaultSkill(name, category, en, ja...)
+	skill.AliasesOnly = true
+	return skill
+}
+
+// DefaultSkills returns the taxonomy used until skills are curated through the API.
+// Words that are common in ordinary sentences (e.g. "go", "express") are only matched in unambiguous forms.
+func DefaultSkills() []Skill {
+	return []Skill{
+		// 言語
+		defaultSkill("Java", SkillCategoryLanguage, nil),
+		defaultSkill("Python", SkillCategoryLanguage, nil, "パイソン"),
+		ambiguousSkill("Go", SkillCategoryLanguage, []string{"golang"}, "Go言語"),
+		defaultSkill("JavaScript", SkillCategoryLanguage, []string{"js", "ecmascript"}),
+		defaultSkill("TypeScript", SkillCategoryLanguage, []string{"ts"}),
+		defaultSkill("PHP", SkillCategoryLanguage, nil),
+		defaultSkill("Ruby", SkillCategoryLanguage, nil),
+		defaultSkill("Scala", SkillCategoryLanguage, nil),
+		defaultSkill("Rust", SkillCategoryLanguage, nil),
+		defaultSkill("C#", SkillCategoryLanguage, []string{"csharp"}),
+		defaultSkill("C++", SkillCategoryLanguage, []string{"cpp"}),
+		defaultSkill("Swift", SkillCategoryLanguage, nil),
+		defaultSkill("Kotlin", SkillCategoryLanguage, nil),
+		defaultSkill("SQL", SkillCategoryLanguage, nil),
+		defaultSkill("HTML", SkillCategoryLanguage, []string{"html5"}),
+		defaultSkill("CSS", SkillCategoryLanguage, []string{"css3"}),
+		defaultSkill("Sass", SkillCategoryLanguage, []string{"scss"}),
+		defaultSkill("Bash", SkillCategoryLanguage, []string{"shell script"}, "シェルスクリプト"),
+		defaultSkill("PowerShell", SkillCategoryLanguage, nil),
+		defaultSkill("Solidity", SkillCategoryLanguage, nil),
+
+		// フレームワーク・ライブラリ
+		defaultSkill("React", SkillCategoryFramework, []string{"react.js", "reactjs"}),
+		defaultSkill("React Native", SkillCategoryFramework, nil),
+		defaultSkill("Vue.js", SkillCategoryFramework, []string{"vue", "vuejs"}),
+		defaultSkill("Angular", SkillCategoryFramework, []string{"angularjs"}),
+		defaultSkill("Node.js", SkillCategoryFramework, []string{"nodejs"}),
+		ambiguousSkill("Express", SkillCategoryFramework, []string{"express.js", "expressjs"}),
+		defaultSkill("Next.js", SkillCategoryFramework, []string{"nextjs"}),
+		defaultSkill("Nuxt", SkillCategoryFramework, []string{"nuxt.js", "nuxtjs"}),
+		defaultSkill("jQuery", SkillCategoryFramework, nil),
+		ambiguousSkill("Bootstrap", SkillCategoryFramework, []string{"bootstrap css", "twitter bootstrap", "bootstrap5"}),
+		defaultSkill("Tailwind CSS", SkillCategoryFramework, []string{"tailwind", "tailwindcss"}),
+		defaultSkill("Laravel", SkillCategoryFramework, nil),
+		defaultSkill("Symfony", SkillCategoryFramework, nil),
+		defaultSkill("Ruby on Rails", SkillCategoryFramework, []string{"rails", "ror"}),
+		defaultSkill(".NET", SkillCategoryFramework, []string{"dotnet", "asp.net"}),
+		defaultSkill("Flutter", SkillCategoryFramework, nil),
+		defaultSkill("Unity", SkillCategoryFramework, nil),
+		defaultSkill("TensorFlow", SkillCategoryFramework, nil),
+		defaultSkill("PyTorch", SkillCategoryFramework, nil),
+		defaultSkill("Jest", SkillCategoryFramework, nil),
+		defaultSkill("Mocha", SkillCategoryFramework, nil),
+		defaultSkill("Selenium", SkillCategoryFramework, nil),
+		defaultSkill("Cypress", SkillCategoryFramework, nil),
+
+		// データベース
+		defaultSkill("MySQL", SkillCategoryDatabase, nil),
+		defaultSkill("PostgreSQL", SkillCategoryDatabase, []string{"postgres"}),
+		defaultSkill("MongoDB", SkillCategoryDatabase, nil),
+		defaultSkill("Oracle Database", SkillCategoryDatabase, []string{"oracle"}),
+		defaultSkill("NoSQL", SkillCategoryDatabase, nil),
+
+		// クラウド・インフラ
+		defaultSkill("AWS", SkillCategoryCloud, []string{"amazon web services"}),
+		defaultSkill("GCP", SkillCategoryCloud, []string{"google cloud", "google cloud platform"}),
+		defaultSkill("Azure", SkillCategoryCloud, []string{"microsoft azure"}),
+		defaultSkill("Docker", SkillCategoryCloud, nil, "コンテナ"),
+		defaultSkill("Kubernetes", SkillCategoryCloud, []string{"k8s"}),
+		defaultSkill("Terraform", SkillCategoryCloud, nil),
+		defaultSkill("Linux", SkillCategoryPlatform, nil),
+		defaultSkill("Unix", SkillCategoryPlatform, nil),
+		defaultSkill("iOS", SkillCategoryPlatform, nil),
+		defaultSkill("Android", SkillCategoryPlatform, nil),
+
+		// ツール
+		defaultSkill("Git", SkillCategoryTool, []string{"github", "gitlab"}),
+		defaultSkill("GraphQL", SkillCategoryTool, nil),
+		defaultSkill("REST API", SkillCategoryTool, []string{"restful", "rest"}),
+		defaultSkill("Kafka", SkillCategoryTool, []string{"apache kafka"}),
+		defaultSkill("Tableau", SkillCategoryTool, nil),
+		defaultSkill("Power BI", SkillCategoryTool, nil),
+		defaultSkill("Figma", SkillCategoryDesign, nil),
+		defaultSkill("Sketch", SkillCategoryDesign, nil),
+		defaultSkill("Adobe XD", SkillCategoryDesign, nil),
+		defaultSkill("Photoshop", SkillCategoryDesign, nil),
+		defaultSkill("Illustrator", SkillCategoryDesign, nil),
+		defaultSkill("UI/UX", SkillCategoryDesign, []string{"ui design", "ux design"}, "UIデザイン", "UXデザイン"),
+
+		// 開発手法
+		defaultSkill("Agile", SkillCategoryPractice, nil, "アジャイル"),
+		defaultSkill("Scrum", SkillCategoryPractice, nil, "スクラム"),
+		defaultSkill("DevOps", SkillCategoryPractice, nil),
+		defaultSkill("CI/CD", SkillCategoryPractice, []string{"continuous integration"}, "継続的インテグレーション"),
+		defaultSkill("Microservices", SkillCategoryPractice, []string{"microservice"}, "マイクロサービス"),
+		defaultSkill("Testing", SkillCategoryPractice, []string{"test automation", "qa"}, "テスト自動化", "品質保証"),
+		defaultSkill("Security", SkillCategoryDomain, []string{"cybersecurity"}, "セキュリティ"),
+		defaultSkill("Networking", SkillCategoryDomain, []string{"network engineering"}, "ネットワーク"),
+
+		// 専門分野
+		defaultSkill("Frontend", SkillCategoryDomain, []string{"front-end", "front end"}, "フロントエンド"),
+		defaultSkill("Backend", SkillCategoryDomain, []string{"back-end", "back end"}, "バックエンド"),
+		defaultSkill("Full Stack", SkillCategoryDomain, []string{"fullstack", "full-stack"}, "フルスタック"),
+		defaultSkill("Game Development", SkillCategoryDomain, []string{"game dev"}, "ゲーム開発"),
+		defaultSkill("Blockchain", SkillCategoryDomain, nil, "ブロックチェーン"),
+		defaultSkill("Ethereum", SkillCategoryDomain, nil, "イーサリアム"),
+		defaultSkill("Smart Contracts", SkillCategoryDomain, []string{"smart contract"}, "スマートコントラクト"),
+		defaultSkill("Web3", SkillCategoryDomain, nil),
+
+		// データ・AI
+		defaultSkill("AI", SkillCategoryDataScience, []string{"artificial intelligence"}, "人工知能"),
+		defaultSkill("Machine Learning", SkillCategoryDataScience, []string{"ml"}, "機械学習"),
+		defaultSkill("Deep Learning", SkillCategoryDataScience, nil, "深層学習", "ディープラーニング"),
+		defaultSkill("NLP", SkillCategoryDataScience, []string{"natural language processing"}, "自然言語処理"),
+		defaultSkill("Computer Vision", SkillCategoryDataScience, nil, "画像認識", "コンピュータビジョン"),
+		defaultSkill("Data Science", SkillCategoryDataScience, nil, "データサイエンス"),
+		defaultSkill("Big Data", SkillCategoryDataScience, nil, "ビッグデータ"),
+		defaultSkill("Hadoop", SkillCategoryDataScience, nil),
+		defaultSkill("Spark", SkillCategoryDataScience, []string{"apache spark", "pyspark"}),
+		defaultSkill("ETL", SkillCategoryDataScience, nil),
+	}
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobStatus is the lifecycle state of a job posting
+type JobStatus string
+
+const (
+	JobStatusDraft     JobStatus = "draft"
+	JobStatusPublished JobStatus = "published"
+	JobStatusPaused    JobStatus = "paused"
+	JobStatusClosed    JobStatus = "closed"
+	JobStatusArchived  JobStatus = "archived"
+)
+
+// JobStatuses lists all statuses in lifecycle order
+var JobStatuses = []JobStatus{JobStatusDraft, JobStatusPublished, JobStatusPaused, JobStatusClosed, JobStatusArchived}
+
+// 状態ごとに遷移できる先の状態
+var jobStatusTransitions = map[JobStatus][]JobStatus{
+	JobStatusDraft:     {JobStatusPublished, JobStatusArchived},
+	JobStatusPublished: {JobStatusPaused, JobStatusClosed},
+	JobStatusPaused:    {JobStatusPublished, JobStatusClosed},
+	JobStatusClosed:    {JobStatusPublished, JobStatusArchived},
+	JobStatusArchived:  {},
+}
+
+// 表示用のラベル
+var jobStatusLabels = map[JobStatus]map[string]string{
+	JobStatusDraft:     {"ja": "下書き", "en": "Draft"},
+	JobStatusPublished: {"ja": "公開中", "en": "Published"},
+	JobStatusPaused:    {"ja": "一時停止中", "en": "Paused"},
+	JobStatusClosed:    {"ja": "募集終了", "en": "Closed"},
+	JobStatusArchived:  {"ja": "アーカイブ済み", "en": "Archived"},
+}
+
+// 以前の自由入力のステータス（シードデータの "Active" など）
+var legacyJobStatuses = map[string]JobStatus{
+	"active":   JobStatusPublished,
+	"open":     JobStatusPublished,
+	"inactive": JobStatusPaused,
+	"募集中":      JobStatusPublished,
+	"停止中":      JobStatusPaused,
+	"終了":       JobStatusClosed,
+}
+
+// 状態変更の理由
+const (
+	StatusChangeManual  = "manual"
+	StatusChangeExpired = "expired"
+	StatusChangeSeed    = "seed"
+)
+
+// JobStatusChange records one status change of a job posting
+type JobStatusChange struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	JobPostingID uint      `gorm:"index" json:"job_posting_id"`
+	FromStatus   JobStatus `gorm:"size:20" json:"from_status"`
+	ToStatus     JobStatus `gorm:"size:20" json:"to_status"`
+	Reason       string    `gorm:"size:20" json:"reason"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// InvalidTransitionError is returned for a status change the lifecycle does not allow
+type InvalidTransitionError struct {
+	From JobStatus
+	To   JobStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot change job status from %s to %s", e.From, e.To)
+}
+
+// ParseJobStatus accepts a status value, one of its labels or a legacy value.
+// An empty string is parsed as draft.
+func ParseJobStatus(s string) (JobStatus, bool) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	if value == "" {
+		return JobStatusDraft, true
+	}
+
+	for _, status := range JobStatuses {
+		if value == string(status) {
+			return status, true
+		}
+		for _, label := range jobStatusLabels[status] {
+			if value == strings.ToLower(label) {
+				return status, true
+			}
+		}
+	}
+
+	status, ok := legacyJobStatuses[value]
+	return status, ok
+}
+
+// Valid reports whether s is one of the defined statuses
+func (s JobStatus) Valid() bool {
+	_, ok := jobStatusTransitions[s]
+	return ok
+}
+
+// Label returns the display label in the given language, falling back to Japanese
+func (s JobStatus) Label(lang string) string {
+	labels, ok := jobStatusLabels[s]
+	if !ok {
+		return string(s)
+	}
+	if label, ok := labels[lang]; ok {
+		return label
+	}
+	return labels["ja"]
+}
+
+// Transitions returns the statuses s can change to
+func (s JobStatus) Transitions() []JobStatus {
+	return jobStatusTransitions[s]
+}
+
+// CanTransitionTo reports whether the lifecycle allows changing from s to to
+func (s JobStatus) CanTransitionTo(to JobStatus) bool {
+	for _, next := range jobStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateJobPosting creates a job posting and records its initial status with reason.
+// The status is normalized by BeforeSave, so an empty status creates a draft.
+func CreateJobPosting(tx *gorm.DB, job *JobPosting, reason string) error {
+	if err := tx.Create(job).Error; err != nil {
+		return err
+	}
+	return tx.Create(&JobStatusChange{
+		JobPostingID: job.ID,
+		ToStatus:     job.Status,
+		Reason:       reason,
+		ChangedAt:    time.Now(),
+	}).Error
+}
+
+// ChangeJobStatus moves a job posting to a new status and records the change.
+// Changing to the current status is a no-op; illegal transitions return *InvalidTransitionError.
+func ChangeJobStatus(tx *gorm.DB, job *JobPosting, to JobStatus, reason string) error {
+	from := job.Status
+	if from == to {
+		return nil
+	}
+	if !from.CanTransitionTo(to) {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+
+	if err := tx.Model(job).Update("status", to).Error; err != nil {
+		return err
+	}
+	job.Status = to
+
+	return tx.Create(&JobStatusChange{
+		JobPostingID: job.ID,
+		FromStatus:   from,
+		ToStatus:     to,
+		Reason:       reason,
+		ChangedAt:    time.Now(),
+	}).Error
+}
+
+// normalizeJobStatuses converts free-text statuses stored before the lifecycle existed.
+// Unknown values become draft so that they are not shown as published by accident.
+func normalizeJobStatuses(db *gorm.DB) error {
+	if err := db.Model(&JobPosting{}).Unscoped().Where("status IS NULL").
+		UpdateColumn("status", JobStatusDraft).Error; err != nil {
+		return err
+	}
+
+	var values []string
+	if err := db.Model(&JobPosting{}).Unscoped().Distinct().Pluck("status", &values).Error; err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if JobStatus(value).Valid() {
+			continue
+		}
+		status, ok := ParseJobStatus(value)
+		if !ok {
+			status = JobStatusDraft
+		}
+		if err := db.Model(&JobPosting{}).Unscoped().Where("status = ?", value).
+			UpdateColumn("status", status).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,14 +1,22 @@
 package scripts
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"howtv-server/controllers"
 	"howtv-server/models"
+	"howtv-server/services"
 
-	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MockCompany struct {
@@ -20,162 +28,281 @@ type MockCompany struct {
 }
 
 type MockJobPosting struct {
-	Title          string `json:"title"`
-	Description    string `json:"description"`
-	Requirements   string `json:"requirements"`
-	SalaryRange    string `json:"salary_range"`
-	Location       string `json:"location"`
-	EmploymentType string `json:"employment_type"`
-	PostingDate    string `json:"posting_date"`
-	ClosingDate    string `json:"closing_date"`
-	Status         string `json:"status"`
+	Title          string   `json:"title"`
+	Description    string   `json:"description"`
+	Requirements   string   `json:"requirements"`
+	SalaryRange    string   `json:"salary_range"`
+	Location       string   `json:"location"`
+	EmploymentType string   `json:"employment_type"`
+	PostingDate    string   `json:"posting_date"`
+	ClosingDate    string   `json:"closing_date"`
+	Status         string   `json:"status"`
+	Positions      []string `json:"positions"`
 }
 
 type MockData struct {
 	Companies []MockCompany `json:"companies"`
 }
 
-func SeedPositions() []models.Position {
-	positions := []models.Position{
-		{Name: "フロントエンドエンジニア"},
-		{Name: "バックエンドエンジニア"},
-		{Name: "フルスタックエンジニア"},
-		{Name: "モバイルエンジニア"},
-		{Name: "インフラエンジニア"},
-		{Name: "データエンジニア"},
-		{Name: "AI/ML エンジニア"},
-		{Name: "DevOpsエンジニア"},
-		{Name: "セキュリティエンジニア"},
-		{Name: "QAエンジニア"},
-	}
-
-	for i := range positions {
-		controllers.DB.FirstOrCreate(&positions[i], models.Position{Name: positions[i].Name})
-	}
+// SeedOptions controls SeedDatabase
+type SeedOptions struct {
+	// Reset deletes the companies and job postings, including those created through the API, before seeding
+	Reset bool
+	// DryRun reports what would change and rolls everything back
+	DryRun bool
+}
 
-	return positions
+// SeedResult reports what SeedDatabase changed
+type SeedResult struct {
+	File             string `json:"file"`
+	Checksum         string `json:"checksum"`
+	Skipped          bool   `json:"skipped"` // 同じ内容のファイルを適用済み
+	CompaniesCreated int    `json:"companies_created"`
+	CompaniesUpdated int    `json:"companies_updated"`
+	JobsCreated      int    `json:"jobs_created"`
+	JobsUpdated      int    `json:"jobs_updated"`
+	PositionsCreated int    `json:"positions_created"`
 }
 
-func SeedDatabase(filePath string) error {
-	// Read mock data file using os.ReadFile instead of deprecated ioutil.ReadFile
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// SeedDatabase applies a mock data file. Companies are identified by name and job postings by company and title,
+// so applying a file again updates the existing rows instead of duplicating them. A file that was already applied
+// with the same content is skipped.
+func SeedDatabase(db *gorm.DB, filePath string, opts SeedOptions) (*SeedResult, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Printf("Error reading mock data file: %v", err)
-		return err
+		return nil, fmt.Errorf("error reading mock data file: %w", err)
 	}
 
-	// Parse mock data
 	var mockData MockData
 	if err := json.Unmarshal(data, &mockData); err != nil {
-		log.Printf("Error parsing mock data: %v", err)
-		return err
+		return nil, fmt.Errorf("error parsing mock data: %w", err)
 	}
 
-	// Seed positions first
-	positions := SeedPositions()
+	sum := sha256.Sum256(data)
+	result := &SeedResult{File: filepath.Base(filePath), Checksum: hex.EncodeToString(sum[:])}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if opts.Reset {
+			if err := resetSeedData(tx); err != nil {
+				return err
+			}
+		} else {
+			var applied models.SeedFile
+			err := tx.Where("name = ?", result.File).Limit(1).Find(&applied).Error
+			if err != nil {
+				return err
+			}
+			if applied.Checksum == result.Checksum {
+				result.Skipped = true
+				return nil
+			}
+		}
+
+		if err := applyMockData(tx, &mockData, result); err != nil {
+			return err
+		}
+		if err := recordSeedFile(tx, result); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
 
-	// Create position map for easy lookup
-	posMap := make(map[string]models.Position)
-	for _, pos := range positions {
-		posMap[pos.Name] = pos
+// applyMockData creates or updates the companies and job postings of the mock data
+func applyMockData(tx *gorm.DB, mockData *MockData, result *SeedResult) error {
+	// 宣言されたポジションは名前・表示名・別名で分類から探す
+	positions, err := models.PositionsByTerm(tx)
+	if err != nil {
+		return fmt.Errorf("error loading positions: %w", err)
 	}
 
 	for _, mockCompany := range mockData.Companies {
-		company := models.Company{
-			Name:     mockCompany.Name,
-			Address:  mockCompany.Address,
-			Industry: mockCompany.Industry,
-			Website:  mockCompany.Website,
+		company, created, err := upsertCompany(tx, mockCompany)
+		if err != nil {
+			return fmt.Errorf("error seeding company %s: %w", mockCompany.Name, err)
 		}
-
-		// Create or find company
-		result := controllers.DB.FirstOrCreate(&company, models.Company{Name: company.Name})
-		if result.Error != nil {
-			log.Printf("Error creating company %s: %v", company.Name, result.Error)
-			continue
+		if created {
+			result.CompaniesCreated++
+		} else {
+			result.CompaniesUpdated++
 		}
 
-		// Create job postings for this company
 		for _, mockJob := range mockCompany.JobPostings {
-			job := models.JobPosting{
-				UUID:           uuid.New(),
-				CompanyID:      company.UUID,
-				Title:          mockJob.Title,
-				Description:    mockJob.Description,
-				Requirements:   mockJob.Requirements,
-				SalaryRange:    mockJob.SalaryRange,
-				Location:       mockJob.Location,
-				EmploymentType: mockJob.EmploymentType,
-				Status:         mockJob.Status,
+			job, created, err := upsertJobPosting(tx, company.ID, mockJob)
+			if err != nil {
+				return fmt.Errorf("error seeding job posting %s: %w", mockJob.Title, err)
 			}
-
-			// Create job posting
-			if err := controllers.DB.Create(&job).Error; err != nil {
-				log.Printf("Error creating job posting: %v", err)
-				continue
+			if created {
+				result.JobsCreated++
+			} else {
+				result.JobsUpdated++
 			}
 
-			// Assign appropriate positions based on title/description
-			var jobPositions []models.Position
-
-			// Example logic to assign positions based on keywords
-			if mockJob.Title == "フルリモートエンジニア" {
-				// For remote positions, check description for specific skills
-				if containsAny(mockJob.Description, []string{"frontend", "UI", "React", "Vue", "JavaScript"}) {
-					jobPositions = append(jobPositions, posMap["フロントエンドエンジニア"])
-				}
-				if containsAny(mockJob.Description, []string{"backend", "server", "API", "Go", "Python", "Java"}) {
-					jobPositions = append(jobPositions, posMap["バックエンドエンジニア"])
-				}
-				if containsAny(mockJob.Description, []string{"full-stack", "full stack", "フルスタック"}) {
-					jobPositions = append(jobPositions, posMap["フルスタックエンジニア"])
-				}
-			} else if mockJob.Title == "東京ポジションエンジニア" {
-				// Similar logic for Tokyo-based positions
-				if containsAny(mockJob.Description, []string{"frontend", "UI", "React", "Vue", "JavaScript"}) {
-					jobPositions = append(jobPositions, posMap["フロントエンドエンジニア"])
-				}
-				if containsAny(mockJob.Description, []string{"backend", "server", "API", "Go", "Python", "Java"}) {
-					jobPositions = append(jobPositions, posMap["バックエンドエンジニア"])
-				}
+			jobPositions, err := seedJobPositions(tx, positions, mockJob.Positions, result)
+			if err != nil {
+				return fmt.Errorf("error creating positions for job posting %s: %w", mockJob.Title, err)
 			}
-
-			// If no specific positions detected, assign a default one based on title
-			if len(jobPositions) == 0 {
-				if containsAny(mockJob.Title, []string{"フル", "full"}) {
-					jobPositions = append(jobPositions, posMap["フルスタックエンジニア"])
-				} else {
-					// Default to both front-end and back-end for general engineering positions
-					jobPositions = append(jobPositions, posMap["フロントエンドエンジニア"])
-					jobPositions = append(jobPositions, posMap["バックエンドエンジニア"])
-				}
+			if err := tx.Model(job).Association("Positions").Replace(jobPositions); err != nil {
+				return fmt.Errorf("error assigning positions to job posting %s: %w", mockJob.Title, err)
 			}
-
-			// Assign positions to job
-			if len(jobPositions) > 0 {
-				if err := controllers.DB.Model(&job).Association("Positions").Append(&jobPositions); err != nil {
-					log.Printf("Error assigning positions to job: %v", err)
-				}
+			if err := services.SyncJobSkills(tx, job); err != nil {
+				return fmt.Errorf("error assigning skills to job posting %s: %w", mockJob.Title, err)
 			}
 		}
 	}
-
-	log.Println("Database seeding completed successfully")
 	return nil
 }
 
-// Helper function to check if a string contains any of the keywords
-func containsAny(s string, keywords []string) bool {
-	for _, keyword := range keywords {
-		if contains(s, keyword) {
-			return true
+// upsertCompany finds the company by name and overwrites its fields with the mock data
+func upsertCompany(tx *gorm.DB, mockCompany MockCompany) (*models.Company, bool, error) {
+	fields := models.Company{
+		Name:     mockCompany.Name,
+		Address:  mockCompany.Address,
+		Industry: mockCompany.Industry,
+		Website:  mockCompany.Website,
+	}
+
+	var company models.Company
+	if err := tx.Where("name = ?", mockCompany.Name).Limit(1).Find(&company).Error; err != nil {
+		return nil, false, err
+	}
+	if company.ID == 0 {
+		err := tx.Create(&fields).Error
+		return &fields, true, err
+	}
+	err := tx.Model(&company).Select("Address", "Industry", "Website").Updates(fields).Error
+	return &company, false, err
+}
+
+// upsertJobPosting finds the job posting by company and title and overwrites its fields with the mock data.
+// Existing postings keep their UUID, so links to them stay valid. Status changes go through the lifecycle
+// like those made through the API; a change the lifecycle does not allow keeps the current status.
+func upsertJobPosting(tx *gorm.DB, companyID uint, mockJob MockJobPosting) (*models.JobPosting, bool, error) {
+	status, ok := models.ParseJobStatus(mockJob.Status)
+	if !ok {
+		return nil, false, fmt.Errorf("invalid job status: %s", mockJob.Status)
+	}
+	fields := models.JobPosting{
+		CompanyID:      companyID,
+		Title:          mockJob.Title,
+		Description:    mockJob.Description,
+		Requirements:   mockJob.Requirements,
+		SalaryRange:    mockJob.SalaryRange,
+		Location:       mockJob.Location,
+		EmploymentType: mockJob.EmploymentType,
+		Status:         status,
+		PostingDate:    parseMockDate(mockJob.PostingDate),
+		ClosingDate:    parseMockDate(mockJob.ClosingDate),
+	}
+
+	var job models.JobPosting
+	if err := tx.Where("company_id = ? AND title = ?", companyID, mockJob.Title).Limit(1).Find(&job).Error; err != nil {
+		return nil, false, err
+	}
+	if job.ID == 0 {
+		err := models.CreateJobPosting(tx, &fields, models.StatusChangeSeed)
+		return &fields, true, err
+	}
+
+	// 給与の構造化カラムは BeforeSave で給与テキストから補完し直す
+	job.Description = fields.Description
+	job.Requirements = fields.Requirements
+	job.SalaryRange = fields.SalaryRange
+	job.SalaryMin, job.SalaryMax, job.Currency, job.Period = 0, 0, "", ""
+	job.Location = fields.Location
+	job.EmploymentType = fields.EmploymentType
+	job.PostingDate = fields.PostingDate
+	job.ClosingDate = fields.ClosingDate
+	if err := tx.Omit(clause.Associations).Save(&job).Error; err != nil {
+		return nil, false, err
+	}
+
+	err := models.ChangeJobStatus(tx, &job, status, models.StatusChangeSeed)
+	var transitionErr *models.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		log.Printf("警告: 求人 %s のステータスは変更できないため %s のままにします: %v", job.Title, job.Status, err)
+		err = nil
+	}
+	return &job, false, err
+}
+
+// resetSeedData deletes the companies and job postings with everything that belongs to them.
+// The position and skill taxonomies are curated separately and are kept.
+func resetSeedData(tx *gorm.DB) error {
+	for _, table := range []string{"job_positions", "job_skills"} {
+		if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
 		}
 	}
-	return false
+	for _, model := range []interface{}{
+		&models.JobStatusChange{}, &models.RoadmapTask{}, &models.Roadmap{},
+		&models.JobPosting{}, &models.Company{}, &models.SeedFile{},
+	} {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordSeedFile stores the checksum of the applied file
+func recordSeedFile(tx *gorm.DB, result *SeedResult) error {
+	var seedFile models.SeedFile
+	if err := tx.Where("name = ?", result.File).Limit(1).Find(&seedFile).Error; err != nil {
+		return err
+	}
+	seedFile.Name = result.File
+	seedFile.Checksum = result.Checksum
+	seedFile.AppliedAt = time.Now()
+	return tx.Save(&seedFile).Error
 }
 
-// Helper function to check if a string contains a keyword
-func contains(s, keyword string) bool {
-	// 文字列検索の改良が必要かも
-	return true // 一旦すべてtrue
+// Helper function to parse an RFC 3339 date; empty or invalid dates become nil
+func parseMockDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("Invalid date in mock data: %q", value)
+		return nil
+	}
+	return &t
+}
+
+// seedJobPositions returns the positions declared for a job posting. Names that are not in the taxonomy
+// are created as top level positions and added to positions, so later postings find them as well.
+func seedJobPositions(tx *gorm.DB, positions map[string]models.Position, names []string, result *SeedResult) ([]models.Position, error) {
+	jobPositions := []models.Position{}
+	seen := map[uint]bool{}
+	for _, name := range names {
+		term := models.NormalizeSkillTerm(name)
+		if term == "" {
+			continue
+		}
+		position, ok := positions[term]
+		if !ok {
+			position = models.Position{Name: strings.TrimSpace(name), NameEn: strings.TrimSpace(name)}
+			if err := tx.Create(&position).Error; err != nil {
+				return nil, err
+			}
+			result.PositionsCreated++
+			positions[term] = position
+		}
+		if !seen[position.ID] {
+			seen[position.ID] = true
+			jobPositions = append(jobPositions, position)
+		}
+	}
+	return jobPositions, nil
 }
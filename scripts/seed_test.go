@@ -0,0 +1,282 @@
+package scripts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// setupSeedDB はマイグレーション済みのインメモリデータベースを作成します
+func setupSeedDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("テスト用データベースの接続に失敗しました: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("マイグレーションに失敗しました: %v", err)
+	}
+	return db
+}
+
+// writeMockData はモックデータを一時ファイルに書き込みます
+func writeMockData(t *testing.T, mock MockData) string {
+	path := filepath.Join(t.TempDir(), "mockdata.json")
+	data, _ := json.Marshal(mock)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("モックデータを書き込めません: %v", err)
+	}
+	return path
+}
+
+// seededPositionTerms は求人に関連付けられたポジションを名前・表示名・別名の集合で返します
+func seededPositionTerms(t *testing.T, db *gorm.DB, title string) []map[string]bool {
+	var job models.JobPosting
+	if err := db.Preload("Positions.Aliases").Where("title = ?", title).First(&job).Error; err != nil {
+		t.Fatalf("求人 %s が作成されていません: %v", title, err)
+	}
+	var terms []map[string]bool
+	for i := range job.Positions {
+		set := map[string]bool{}
+		for _, term := range job.Positions[i].Terms() {
+			set[term] = true
+		}
+		terms = append(terms, set)
+	}
+	return terms
+}
+
+// TestSeedDatabasePositions はmockdata.txtで宣言されたポジションがそのまま関連付けられることをテストします
+func TestSeedDatabasePositions(t *testing.T) {
+	db := setupSeedDB(t)
+
+	if _, err := SeedDatabase(db, "../mockdata.txt", SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+
+	data, err := os.ReadFile("../mockdata.txt")
+	if err != nil {
+		t.Fatalf("モックデータを読み込めません: %v", err)
+	}
+	var mockData MockData
+	if err := json.Unmarshal(data, &mockData); err != nil {
+		t.Fatalf("モックデータのパースに失敗しました: %v", err)
+	}
+
+	for _, company := range mockData.Companies {
+		for _, mockJob := range company.JobPostings {
+			terms := seededPositionTerms(t, db, mockJob.Title)
+			if !assert.Len(t, terms, len(mockJob.Positions), mockJob.Title) {
+				continue
+			}
+			// 宣言されたポジションが同義語で解決されて関連付けられる
+			for _, name := range mockJob.Positions {
+				found := false
+				for _, set := range terms {
+					found = found || set[models.NormalizeSkillTerm(name)]
+				}
+				assert.True(t, found, "%s: %s", mockJob.Title, name)
+			}
+		}
+	}
+
+	// 既定の分類にあるポジションは新たに作られない
+	var cloud models.Position
+	db.Where("name_en = ?", "Cloud Engineer").First(&cloud)
+	assert.Equal(t, "クラウドエンジニア", cloud.Name)
+	var count int64
+	db.Model(&models.Position{}).Where("name = ?", "Cloud Engineer").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// TestSeedDatabaseCreatesMissingPositions は分類にないポジションが一度だけ作成されることをテストします
+func TestSeedDatabaseCreatesMissingPositions(t *testing.T) {
+	db := setupSeedDB(t)
+
+	mock := MockData{Companies: []MockCompany{{
+		Name: "シード株式会社",
+		JobPostings: []MockJobPosting{
+			{Title: "セールスエンジニア", Positions: []string{"Sales Engineer", "backend engineer", "サーバーサイドエンジニア"}},
+			{Title: "プリセールス", Positions: []string{"sales engineer"}},
+		},
+	}}}
+	path := writeMockData(t, mock)
+
+	if _, err := SeedDatabase(db, path, SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+
+	var job models.JobPosting
+	db.Preload("Positions").Where("title = ?", "セールスエンジニア").First(&job)
+	var names []string
+	for _, position := range job.Positions {
+		names = append(names, position.Name)
+	}
+	sort.Strings(names)
+	// 同じポジションを指す別名は1つにまとめる
+	assert.Equal(t, []string{"Sales Engineer", "バックエンドエンジニア"}, names)
+
+	var count int64
+	db.Model(&models.Position{}).Where("name = ?", "Sales Engineer").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	terms := seededPositionTerms(t, db, "プリセールス")
+	if assert.Len(t, terms, 1) {
+		assert.True(t, terms[0]["sales engineer"])
+	}
+}
+
+// countRows はモデルの行数を返します
+func countRows(db *gorm.DB, model interface{}) int64 {
+	var count int64
+	db.Model(model).Count(&count)
+	return count
+}
+
+// TestSeedDatabaseIdempotent は再実行で求人が重複せず、内容が変わった場合は更新されることをテストします
+func TestSeedDatabaseIdempotent(t *testing.T) {
+	db := setupSeedDB(t)
+
+	first, err := SeedDatabase(db, "../mockdata.txt", SeedOptions{})
+	if err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	assert.False(t, first.Skipped)
+	assert.Equal(t, 21, first.JobsCreated)
+	jobs := countRows(db, &models.JobPosting{})
+	companies := countRows(db, &models.Company{})
+
+	// 同じ内容のファイルは適用済みとしてスキップする
+	second, err := SeedDatabase(db, "../mockdata.txt", SeedOptions{})
+	if err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	assert.True(t, second.Skipped)
+	assert.Equal(t, first.Checksum, second.Checksum)
+	assert.Equal(t, jobs, countRows(db, &models.JobPosting{}))
+
+	// 内容が変わったファイルは自然キーで既存の行を更新する
+	mock := MockData{Companies: []MockCompany{{
+		Name:        "シード株式会社",
+		JobPostings: []MockJobPosting{{Title: "Goエンジニア", Requirements: "Golangの実務経験", SalaryRange: "¥6M - ¥8M annually", Status: "Active", Positions: []string{"Backend Engineer"}}},
+	}}}
+	path := writeMockData(t, mock)
+	if _, err := SeedDatabase(db, path, SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	var job models.JobPosting
+	db.Where("title = ?", "Goエンジニア").First(&job)
+
+	mock.Companies[0].JobPostings[0].SalaryRange = "¥7M - ¥9M annually"
+	mock.Companies[0].JobPostings[0].Positions = []string{"Cloud Engineer"}
+	path = writeMockData(t, mock)
+	result, err := SeedDatabase(db, path, SeedOptions{})
+	if err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	assert.Equal(t, 0, result.JobsCreated)
+	assert.Equal(t, 1, result.JobsUpdated)
+
+	var updated models.JobPosting
+	db.Preload("Positions").Preload("Skills").Where("title = ?", "Goエンジニア").First(&updated)
+	assert.Equal(t, job.UUID, updated.UUID)
+	assert.Equal(t, int64(7000000), updated.SalaryMin)
+	if assert.Len(t, updated.Positions, 1) {
+		assert.Equal(t, "クラウドエンジニア", updated.Positions[0].Name)
+	}
+	if assert.NotEmpty(t, updated.Skills) {
+		assert.Equal(t, "Go", updated.Skills[0].Name)
+	}
+	assert.Equal(t, companies+1, countRows(db, &models.Company{}))
+	assert.Equal(t, jobs+1, countRows(db, &models.JobPosting{}))
+}
+
+// TestSeedDatabaseDryRunAndReset はdry-runで何も保存されず、resetで作り直されることをテストします
+func TestSeedDatabaseDryRunAndReset(t *testing.T) {
+	db := setupSeedDB(t)
+
+	result, err := SeedDatabase(db, "../mockdata.txt", SeedOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	assert.Equal(t, 21, result.JobsCreated)
+	assert.Equal(t, int64(0), countRows(db, &models.JobPosting{}))
+	assert.Equal(t, int64(0), countRows(db, &models.SeedFile{}))
+
+	if _, err := SeedDatabase(db, "../mockdata.txt", SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	// APIで追加された求人もresetで消える
+	db.Create(&models.JobPosting{Title: "手動で追加した求人"})
+
+	result, err = SeedDatabase(db, "../mockdata.txt", SeedOptions{Reset: true})
+	if err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 21, result.JobsCreated)
+	assert.Equal(t, int64(21), countRows(db, &models.JobPosting{}))
+	assert.Equal(t, int64(1), countRows(db, &models.SeedFile{}))
+}
+
+// TestSeedDatabaseStatusHistory はシードでもAPIと同じく状態の変更履歴が残ることをテストします
+func TestSeedDatabaseStatusHistory(t *testing.T) {
+	db := setupSeedDB(t)
+
+	mock := MockData{Companies: []MockCompany{{
+		Name:        "シード株式会社",
+		JobPostings: []MockJobPosting{{Title: "Goエンジニア", Status: "Active"}},
+	}}}
+	if _, err := SeedDatabase(db, writeMockData(t, mock), SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	var job models.JobPosting
+	db.Where("title = ?", "Goエンジニア").First(&job)
+	assert.Equal(t, models.JobStatusPublished, job.Status)
+
+	history := func() []models.JobStatusChange {
+		var changes []models.JobStatusChange
+		db.Where("job_posting_id = ?", job.ID).Order("id").Find(&changes)
+		return changes
+	}
+	// 作成時は初期ステータスを記録する
+	if changes := history(); assert.Len(t, changes, 1) {
+		assert.Equal(t, models.JobStatus(""), changes[0].FromStatus)
+		assert.Equal(t, models.JobStatusPublished, changes[0].ToStatus)
+		assert.Equal(t, models.StatusChangeSeed, changes[0].Reason)
+	}
+
+	// 状態の変更はライフサイクルに沿って記録する
+	mock.Companies[0].JobPostings[0].Status = "closed"
+	if _, err := SeedDatabase(db, writeMockData(t, mock), SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	if changes := history(); assert.Len(t, changes, 2) {
+		assert.Equal(t, models.JobStatusPublished, changes[1].FromStatus)
+		assert.Equal(t, models.JobStatusClosed, changes[1].ToStatus)
+		assert.Equal(t, models.StatusChangeSeed, changes[1].Reason)
+	}
+
+	// ライフサイクルで許されない変更は行わず、現在のステータスを保つ
+	mock.Companies[0].JobPostings[0].Status = "draft"
+	mock.Companies[0].JobPostings[0].Description = "更新後の説明"
+	if _, err := SeedDatabase(db, writeMockData(t, mock), SeedOptions{}); err != nil {
+		t.Fatalf("シードに失敗しました: %v", err)
+	}
+	db.First(&job, job.ID)
+	assert.Equal(t, models.JobStatusClosed, job.Status)
+	assert.Equal(t, "更新後の説明", job.Description)
+	assert.Len(t, history(), 2)
+
+	// 不明なステータスはエラー
+	mock.Companies[0].JobPostings[0].Status = "unknown"
+	_, err := SeedDatabase(db, writeMockData(t, mock), SeedOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrCallbackAddressNotAllowed is returned when a callback URL points to a loopback, link-local, private or unspecified address
+var ErrCallbackAddressNotAllowed = errors.New("コールバック先に内部ネットワークのアドレスは指定できません")
+
+const callbackDialTimeout = 10 * time.Second
+
+// ValidateCallbackHost resolves the host of a callback URL and rejects it when any of its addresses is internal
+func ValidateCallbackHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return checkCallbackIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("コールバック先のホストを解決できません: %w", err)
+	}
+	for _, addr := range addrs {
+		if err := checkCallbackIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCallbackIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s", ErrCallbackAddressNotAllowed, ip)
+	}
+	return nil
+}
+
+// callbackDialControl checks the address actually being connected to,
+// so that a host re-resolving to an internal address after ValidateCallbackHost (DNS rebinding) is still rejected.
+func callbackDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrCallbackAddressNotAllowed, host)
+	}
+	return checkCallbackIP(ip)
+}
+
+// newCallbackTransport returns a transport that only connects to public addresses.
+// Proxies are not used because the check would then apply to the proxy instead of the callback host.
+func newCallbackTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout: callbackDialTimeout,
+		Control: callbackDialControl,
+	}).DialContext
+	return transport
+}
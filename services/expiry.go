@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"howtv-server/models"
+
+	"gorm.io/gorm"
+)
+
+// ExpireJobPostings closes the published or paused postings whose closing date has passed
+// and returns how many were closed. Each change is recorded in the status history.
+func ExpireJobPostings(db *gorm.DB, now time.Time) (int64, error) {
+	var jobs []models.JobPosting
+	if err := db.Where("closing_date IS NOT NULL AND closing_date < ? AND status IN ?",
+		now.UTC(), []models.JobStatus{models.JobStatusPublished, models.JobStatusPaused}).
+		Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+
+	var closed int64
+	for i := range jobs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return models.ChangeJobStatus(tx, &jobs[i], models.JobStatusClosed, models.StatusChangeExpired)
+		})
+		if err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// RunExpiryScheduler closes expired postings immediately and then at every interval until ctx is cancelled
+func RunExpiryScheduler(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		closed, err := ExpireJobPostings(db, time.Now())
+		if err != nil {
+			log.Printf("募集期限切れの求人のクローズに失敗しました: %v", err)
+		} else if closed > 0 {
+			log.Printf("募集期限切れの求人を%d件クローズしました", closed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
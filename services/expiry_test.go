@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// TestExpireJobPostings は募集期限切れの求人だけがクローズされることをテストします
+func TestExpireJobPostings(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("テスト用データベースの接続に失敗しました: %v", err)
+	}
+	db.AutoMigrate(&models.Company{}, &models.JobPosting{}, &models.Position{}, &models.JobStatusChange{})
+
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	// JSTで保存された日時もUTCとして比較される
+	jst := time.FixedZone("JST", 9*60*60)
+	pastJST := now.Add(-time.Hour).In(jst)
+
+	jobs := []models.JobPosting{
+		{Title: "期限切れ", Status: "Active", ClosingDate: &past},
+		{Title: "期限切れ(JST)", Status: "Active", ClosingDate: &pastJST},
+		{Title: "募集中", Status: "Active", ClosingDate: &future},
+		{Title: "期限なし", Status: "Active"},
+	}
+	for i := range jobs {
+		db.Create(&jobs[i])
+	}
+
+	closed, err := ExpireJobPostings(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), closed)
+
+	var statuses []models.JobStatus
+	db.Model(&models.JobPosting{}).Order("id").Pluck("status", &statuses)
+	assert.Equal(t, []models.JobStatus{models.JobStatusClosed, models.JobStatusClosed, models.JobStatusPublished, models.JobStatusPublished}, statuses)
+
+	// クローズは状態変更履歴に記録される
+	var changes []models.JobStatusChange
+	db.Order("id").Find(&changes)
+	if assert.Equal(t, 2, len(changes)) {
+		assert.Equal(t, models.JobStatusPublished, changes[0].FromStatus)
+		assert.Equal(t, models.JobStatusClosed, changes[0].ToStatus)
+		assert.Equal(t, models.StatusChangeExpired, changes[0].Reason)
+	}
+
+	// 2回目は何も変更しない
+	closed, err = ExpireJobPostings(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), closed)
+}
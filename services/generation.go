@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrModelNotAllowed is returned when a request selects a model that is not in the allowlist
+var ErrModelNotAllowed = errors.New("指定されたモデルは利用できません")
+
+// GenerationParams are the model and parameters actually used to generate a roadmap
+type GenerationParams struct {
+	Model       string  `json:"model"`
+	Temperature float32 `json:"temperature"`
+	MaxTokens   int     `json:"max_tokens,omitempty"` // 0 はモデルの既定値
+	TopP        float32 `json:"top_p,omitempty"`      // 0 はモデルの既定値
+}
+
+// GenerationSettings overrides generation parameters. Empty and nil fields are inherited.
+type GenerationSettings struct {
+	Model       string
+	Temperature *float32
+	MaxTokens   int
+	TopP        *float32
+}
+
+// GenerationConfig selects the model and parameters per question type
+type GenerationConfig struct {
+	// Default applies to every question type
+	Default GenerationSettings
+	// QuestionTypes overrides Default per question type
+	QuestionTypes map[string]GenerationSettings
+	// AllowedModels lists the models a request may select in addition to the configured ones
+	AllowedModels []string
+}
+
+// 質問タイプごとの既定のtemperature
+var defaultTemperatures = map[string]float32{
+	"skills":   0.5, // スキルは正確さが重要なので低め
+	"learning": 0.7, // 学習方法は少し創造的に
+	"career":   0.8, // キャリアパスはより多様な可能性を示すために高め
+	"general":  0.6, // 一般的な質問の場合
+}
+
+// Resolve returns the parameters for the question type. providerModel is used when no model is configured.
+// model selects another model per request; it must be configured or allowlisted.
+func (g GenerationConfig) Resolve(questionType, model, providerModel string) (GenerationParams, error) {
+	temperature, ok := defaultTemperatures[questionType]
+	if !ok {
+		temperature = defaultTemperatures[DefaultQuestionType]
+	}
+	params := GenerationParams{Model: providerModel, Temperature: temperature}
+	params.apply(g.Default)
+	params.apply(g.QuestionTypes[questionType])
+
+	if model != "" && model != params.Model {
+		if !g.allowed(model) {
+			return GenerationParams{}, fmt.Errorf("%w: %s", ErrModelNotAllowed, model)
+		}
+		params.Model = model
+	}
+	return params, nil
+}
+
+func (g GenerationConfig) allowed(model string) bool {
+	if containsString(g.AllowedModels, model) || g.Default.Model == model {
+		return true
+	}
+	for _, settings := range g.QuestionTypes {
+		if settings.Model == model {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *GenerationParams) apply(s GenerationSettings) {
+	if s.Model != "" {
+		p.Model = s.Model
+	}
+	if s.Temperature != nil {
+		p.Temperature = *s.Temperature
+	}
+	if s.MaxTokens > 0 {
+		p.MaxTokens = s.MaxTokens
+	}
+	if s.TopP != nil {
+		p.TopP = *s.TopP
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// TestGenerationConfigResolve は質問タイプごとのパラメーターとモデルの指定をテストします
+func TestGenerationConfigResolve(t *testing.T) {
+	low, topP := float32(0.2), float32(0.9)
+	cfg := GenerationConfig{
+		Default:       GenerationSettings{MaxTokens: 2000, TopP: &topP},
+		QuestionTypes: map[string]GenerationSettings{"skills": {Model: "gpt-4o", Temperature: &low}},
+		AllowedModels: []string{"gpt-4o-mini"},
+	}
+
+	// 既定のtemperatureは質問タイプごと
+	params, err := cfg.Resolve("career", "", "provider-model")
+	assert.NoError(t, err)
+	assert.Equal(t, GenerationParams{Model: "provider-model", Temperature: 0.8, MaxTokens: 2000, TopP: 0.9}, params)
+
+	params, err = cfg.Resolve("skills", "", "provider-model")
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", params.Model)
+	assert.Equal(t, float32(0.2), params.Temperature)
+
+	// 許可されたモデルと設定済みのモデルは指定できる
+	params, err = cfg.Resolve("general", "gpt-4o-mini", "provider-model")
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", params.Model)
+	params, err = cfg.Resolve("general", "gpt-4o", "provider-model")
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", params.Model)
+
+	_, err = cfg.Resolve("general", "gpt-4", "provider-model")
+	assert.True(t, errors.Is(err, ErrModelNotAllowed))
+	assert.Equal(t, ErrorCodeModelNotAllowed, RoadmapErrorCode(err))
+}
+
+// TestGenerationParamsPersisted は生成に使ったパラメーターがリクエストとキャッシュに反映されることをテストします
+func TestGenerationParamsPersisted(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	provider := &recordingProvider{}
+	service := NewRoadmapService(provider).UseGenerationConfig(GenerationConfig{
+		Default:       GenerationSettings{MaxTokens: 1500},
+		AllowedModels: []string{"other-model"},
+	})
+
+	roadmap, _, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, RoadmapOptions{QuestionType: "learning", Model: "other-model"}, false)
+	assert.NoError(t, err)
+	if assert.Len(t, provider.requests, 1) {
+		assert.Equal(t, "other-model", provider.requests[0].Model)
+		assert.Equal(t, float32(0.7), provider.requests[0].Temperature)
+		assert.Equal(t, 1500, provider.requests[0].MaxTokens)
+	}
+	assert.Equal(t, "other-model", roadmap.Model)
+	assert.Equal(t, float32(0.7), roadmap.Temperature)
+	assert.Equal(t, 1500, roadmap.MaxTokens)
+
+	// モデルごとに別のキャッシュになる
+	_, cached, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, RoadmapOptions{QuestionType: "learning"}, false)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "recording-model", provider.requests[1].Model)
+}
+
+// TestGenerationParamsCacheKey はパラメーターの設定を変えるとキャッシュを使わずに生成し直すことをテストします
+func TestGenerationParamsCacheKey(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	provider := &recordingProvider{}
+	opts := RoadmapOptions{QuestionType: "learning"}
+	service := NewRoadmapService(provider)
+	_, _, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, opts, false)
+	assert.NoError(t, err)
+	_, cached, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, opts, false)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+
+	// 同じモデルでもtemperature・max_tokens・top_pが変われば別のキャッシュになる
+	low, topP := float32(0.2), float32(0.5)
+	for _, settings := range []GenerationSettings{{Temperature: &low}, {MaxTokens: 500}, {TopP: &topP}} {
+		service.UseGenerationConfig(GenerationConfig{Default: settings})
+		roadmap, cached, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, opts, false)
+		assert.NoError(t, err)
+		assert.False(t, cached, "%+v", settings)
+		assert.Equal(t, "recording-model", roadmap.Model)
+	}
+	assert.Len(t, provider.requests, 4)
+}
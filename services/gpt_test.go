@@ -20,26 +20,38 @@ func (m *MockOpenAIService) GenerateCareerRoadmap(job *models.JobPosting, questi
 
 // TestParseRoadmapResponse はparseRoadmapResponse関数をテストします
 func TestParseRoadmapResponse(t *testing.T) {
-	content := `
-# フロントエンドエンジニアのロードマップ
-
-## 必要なスキル
-- HTML/CSS
-- JavaScript
-- React
-
-## 学習タイムライン
-1. 基礎学習 (1-2ヶ月)
-2. フレームワーク学習 (2-3ヶ月)
-3. プロジェクト作成 (1-2ヶ月)
-`
+	content := `{
+  "title": "フロントエンドエンジニアのロードマップ",
+  "summary": "基礎からReactまで段階的に学習します",
+  "timeline": [
+    {"title": "基礎学習", "description": "HTML/CSSとJavaScript", "duration_weeks": 6, "goals": ["静的サイトを作れる"]},
+    {"title": "フレームワーク学習", "description": "React", "duration_weeks": 10}
+  ],
+  "skills": [
+    {"name": "JavaScript", "priority": "high", "target_level": "advanced", "reason": "必須条件"},
+    {"name": "React", "priority": "high", "target_level": "intermediate"}
+  ],
+  "resources": [
+    {"title": "MDN Web Docs", "type": "documentation", "url": "https://developer.mozilla.org/ja/", "skill": "JavaScript"}
+  ]
+}`
 
 	// 関数を実行
-	response := parseRoadmapResponse(content)
+	response, err := parseRoadmapResponse(content)
 
 	// 結果を検証
+	assert.NoError(t, err)
 	assert.NotNil(t, response)
-	assert.Equal(t, content, response.Roadmap)
+	assert.Len(t, response.Timeline, 2)
+	assert.Equal(t, 16, response.TotalWeeks())
+	assert.Equal(t, "JavaScript", response.Skills[0].Name)
+	assert.Equal(t, "advanced", response.Skills[0].TargetLevel)
+
+	// マークダウン表示
+	markdown := response.RenderMarkdown()
+	assert.Contains(t, markdown, "# フロントエンドエンジニアのロードマップ")
+	assert.Contains(t, markdown, "## 学習タイムライン（合計16週間）")
+	assert.Contains(t, markdown, "[MDN Web Docs](https://developer.mozilla.org/ja/)")
 }
 
 // TestGeneratePromptByQuestionType は各質問タイプに対するプロンプト生成を検証します
@@ -54,10 +66,9 @@ func TestGeneratePromptByQuestionType(t *testing.T) {
 		Location:       "東京都渋谷区",
 		EmploymentType: "正社員",
 		Status:         "公開中",
+		Positions:      []models.Position{{Name: "フロントエンドエンジニア"}},
 	}
 
-	positionNames := []string{"フロントエンドエンジニア"}
-
 	// 各質問タイプのテスト
 	testCases := []struct {
 		name         string
@@ -88,8 +99,11 @@ func TestGeneratePromptByQuestionType(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			// プロンプトを生成
-			prompt := generatePromptByQuestionType(job, positionNames, tc.questionType)
+			// プロンプトを生成（テンプレート未指定の場合は既定のテンプレートが使われる）
+			resolved, err := ResolvePrompt(RoadmapOptions{QuestionType: tc.questionType})
+			assert.NoError(t, err)
+			_, prompt, err := resolved.Render(job)
+			assert.NoError(t, err)
 
 			// 共通の情報が含まれていることを確認
 			assert.Contains(t, prompt, "フロントエンドエンジニア")
@@ -170,8 +184,8 @@ func TestOpenAIServiceMock(t *testing.T) {
 					// モックのレスポンスを返す
 					return &RoadmapResponse{
 						Roadmap:  content,
-						Skills:   "Go, API設計, データベース, Docker",
-						Timeline: "3-6ヶ月",
+						Skills:   []RoadmapSkill{{Name: "Go", Priority: SkillPriorityHigh, TargetLevel: "advanced"}},
+						Timeline: []RoadmapPhase{{Title: "基礎学習", DurationWeeks: 12}},
 					}, nil
 				},
 			}
@@ -211,8 +225,8 @@ func TestGenerateCareerRoadmapWithDifferentQuestionTypes(t *testing.T) {
 			// 質問タイプを含むダミーレスポンスを返す
 			return &RoadmapResponse{
 				Roadmap:  "Question Type: " + qt + "\nTitle: " + j.Title,
-				Skills:   "Python, TensorFlow, PyTorch, SQL",
-				Timeline: "6-12ヶ月",
+				Skills:   []RoadmapSkill{{Name: "Python", Priority: SkillPriorityHigh, TargetLevel: "advanced"}},
+				Timeline: []RoadmapPhase{{Title: "基礎学習", DurationWeeks: 24}},
 			}, nil
 		},
 	}
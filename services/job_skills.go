@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"howtv-server/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownSkill is returned when a skill ID does not exist in the taxonomy
+var ErrUnknownSkill = errors.New("スキルが見つかりません")
+
+// SyncJobSkills replaces the skills of the job with the skills of the taxonomy found in its title, requirements
+// and description. Jobs whose skills were edited manually are left as they are.
+func SyncJobSkills(db *gorm.DB, job *models.JobPosting) error {
+	if job.SkillsLocked {
+		return nil
+	}
+
+	var names []string
+	for _, match := range MatchJobSkills(job) {
+		names = append(names, match.Name)
+	}
+	var skills []models.Skill
+	if len(names) > 0 {
+		if err := db.Where("name IN ?", names).Find(&skills).Error; err != nil {
+			return err
+		}
+	}
+	return replaceJobSkills(db, job, skills)
+}
+
+// SetJobSkills sets the skills of the job by hand and stops SyncJobSkills from overwriting them
+func SetJobSkills(db *gorm.DB, job *models.JobPosting, skillIDs []uint) error {
+	var skills []models.Skill
+	if len(skillIDs) > 0 {
+		if err := db.Where("id IN ?", skillIDs).Find(&skills).Error; err != nil {
+			return err
+		}
+	}
+	if missing := missingSkillIDs(skillIDs, skills); len(missing) > 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownSkill, missing)
+	}
+
+	if err := db.Model(job).Update("skills_locked", true).Error; err != nil {
+		return err
+	}
+	return replaceJobSkills(db, job, skills)
+}
+
+// UnlockJobSkills lets the skills of the job be extracted automatically again and extracts them
+func UnlockJobSkills(db *gorm.DB, job *models.JobPosting) error {
+	if err := db.Model(job).Update("skills_locked", false).Error; err != nil {
+		return err
+	}
+	return SyncJobSkills(db, job)
+}
+
+func replaceJobSkills(db *gorm.DB, job *models.JobPosting, skills []models.Skill) error {
+	if len(skills) == 0 {
+		return db.Model(job).Association("Skills").Clear()
+	}
+	return db.Model(job).Association("Skills").Replace(skills)
+}
+
+func missingSkillIDs(ids []uint, skills []models.Skill) []uint {
+	found := make(map[uint]bool, len(skills))
+	for _, skill := range skills {
+		found[skill.ID] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLMプロバイダーの種類
+const (
+	ProviderOpenAI           = "openai"
+	ProviderOpenAICompatible = "openai-compatible"
+	ProviderStub             = "stub"
+)
+
+// ChatMessage is one message of a chat completion request
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatRequest is a provider independent chat completion request
+type ChatRequest struct {
+	Messages []ChatMessage
+	// Model overrides the provider's model when it is not empty
+	Model       string
+	Temperature float32
+	MaxTokens   int     // 0 はモデルの既定値
+	TopP        float32 // 0 はモデルの既定値
+	// JSONMode asks the model to answer with a single JSON object
+	JSONMode bool
+}
+
+// LLMProvider sends chat completion requests to a language model
+type LLMProvider interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, req ChatRequest) (string, error)
+	// Stream passes each generated token to onToken and returns the whole completion.
+	// An error returned by onToken stops the stream.
+	Stream(ctx context.Context, req ChatRequest, onToken func(string) error) (string, error)
+}
+
+// LLM呼び出しの既定値
+const (
+	DefaultLLMTimeout    = 60 * time.Second
+	DefaultLLMMaxRetries = 3
+)
+
+// LLMConfig configures an LLMProvider
+type LLMConfig struct {
+	Provider string // openai, openai-compatible, stub
+	APIKey   string
+	BaseURL  string // openai-compatible の接続先
+	Model    string
+	// Timeout limits each call including its retries; 0 uses DefaultLLMTimeout
+	Timeout time.Duration
+	// MaxRetries is the number of retries on network errors, 429 and 5xx; negative disables retries
+	MaxRetries int
+	// RetryBaseDelay is the first backoff delay; 0 uses the default
+	RetryBaseDelay time.Duration
+}
+
+// OpenAIProvider talks to the OpenAI API or any server implementing the same chat completions API
+type OpenAIProvider struct {
+	client  *openai.Client
+	model   string
+	name    string
+	timeout time.Duration
+}
+
+// NewOpenAIProvider creates a provider for the OpenAI API
+func NewOpenAIProvider(cfg LLMConfig) *OpenAIProvider {
+	if cfg.Model == "" {
+		cfg.Model = openai.GPT3Dot5Turbo
+	}
+	return newOpenAIProvider(openai.DefaultConfig(cfg.APIKey), cfg, ProviderOpenAI)
+}
+
+// NewOpenAICompatibleProvider creates a provider for a local server exposing an OpenAI compatible API,
+// such as Ollama (http://localhost:11434/v1) or llama.cpp (http://localhost:8080/v1)
+func NewOpenAICompatibleProvider(cfg LLMConfig) *OpenAIProvider {
+	clientConfig := openai.DefaultConfig(cfg.APIKey)
+	clientConfig.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return newOpenAIProvider(clientConfig, cfg, ProviderOpenAICompatible)
+}
+
+func newOpenAIProvider(clientConfig openai.ClientConfig, cfg LLMConfig, name string) *OpenAIProvider {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultLLMTimeout
+	}
+	clientConfig.HTTPClient = &http.Client{Transport: newRetryTransport(max(cfg.MaxRetries, 0), cfg.RetryBaseDelay)}
+	return &OpenAIProvider{
+		client:  openai.NewClientWithConfig(clientConfig),
+		model:   cfg.Model,
+		name:    name,
+		timeout: cfg.Timeout,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
+func (p *OpenAIProvider) chatRequest(req ChatRequest) openai.ChatCompletionRequest {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	model := p.model
+	if req.Model != "" {
+		model = req.Model
+	}
+	chatReq := openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+	}
+	if req.JSONMode {
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return chatReq
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resp, err := p.client.CreateChatCompletion(ctx, p.chatRequest(req))
+	if err != nil {
+		return "", classifyLLMError(err)
+	}
+	reportTokenUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return "", errors.New("LLMから応答がありませんでした")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (p *OpenAIProvider) Stream(ctx context.Context, req ChatRequest, onToken func(string) error) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	chatReq := p.chatRequest(req)
+	chatReq.Stream = true
+	// 最後のチャンクでトークン数を受け取る
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		return "", classifyLLMError(err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return content.String(), nil
+		}
+		if err != nil {
+			return content.String(), classifyLLMError(err)
+		}
+		if resp.Usage != nil {
+			reportTokenUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		token := resp.Choices[0].Delta.Content
+		content.WriteString(token)
+		if err := onToken(token); err != nil {
+			return content.String(), err
+		}
+	}
+}
+
+// StubProvider returns a fixed roadmap built from the request without calling any model.
+// The same request always produces the same response, so it can be used in CI and offline development.
+type StubProvider struct{}
+
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+func (p *StubProvider) Name() string {
+	return ProviderStub
+}
+
+func (p *StubProvider) Model() string {
+	return ProviderStub
+}
+
+// Stream sends the stub roadmap line by line
+func (p *StubProvider) Stream(ctx context.Context, req ChatRequest, onToken func(string) error) (string, error) {
+	content, err := p.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := onToken(line); err != nil {
+			return "", err
+		}
+	}
+	return content, nil
+}
+
+func (p *StubProvider) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// 最後のユーザーメッセージから求人の職種を取り出す。
+	// 求人情報は言語に関わらず「項目名: 値」の行で、職種が最初に来る
+	title := ""
+	for _, m := range req.Messages {
+		if m.Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		for _, line := range strings.Split(m.Content, "\n") {
+			if _, value, ok := strings.Cut(line, ": "); ok {
+				title = value
+				break
+			}
+		}
+	}
+	if title == "" {
+		title = "この職種"
+	}
+
+	roadmap := RoadmapResponse{
+		Title:   title + "のロードマップ",
+		Summary: "求人の必須条件を中心に、基礎学習から応募準備までを段階的に進めます。",
+		Timeline: []RoadmapPhase{
+			{Title: "基礎学習", Description: "必須条件に記載された技術の基礎を固める", DurationWeeks: 6, Goals: []string{"基本的な文法と概念を説明できる"}},
+			{Title: "実践プロジェクト", Description: "求人内容に近いアプリケーションを作成する", DurationWeeks: 10, Goals: []string{"ポートフォリオを1つ公開する"}},
+			{Title: "応募準備", Description: "職務経歴書の作成と面接対策", DurationWeeks: 4, Goals: []string{"応募書類を完成させる"}},
+		},
+		Skills: []RoadmapSkill{
+			{Name: "求人の必須条件に記載された技術", Priority: SkillPriorityHigh, TargetLevel: "intermediate", Reason: "選考で必ず確認される"},
+			{Name: "チーム開発の経験", Priority: SkillPriorityMedium, TargetLevel: "beginner", Reason: "Gitやコードレビューは日常的に使う"},
+		},
+		Resources: []RoadmapResource{
+			{Title: "公式ドキュメント", Type: "documentation"},
+		},
+	}
+	// 応募者のプロフィールが渡された場合はスキルギャップを返す
+	for _, m := range req.Messages {
+		if strings.Contains(m.Content, "skill_gaps") {
+			roadmap.SkillGaps = []RoadmapSkillGap{
+				{Skill: "求人の必須条件に記載された技術", CurrentLevel: "beginner", RequiredLevel: "intermediate", Weeks: 16, Advice: "実践プロジェクトで経験を補う"},
+			}
+			break
+		}
+	}
+	var content string
+	if req.JSONMode {
+		b, err := json.MarshalIndent(roadmap, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		content = string(b)
+	} else {
+		content = roadmap.RenderMarkdown()
+	}
+
+	// 利用量の確認用に、文字数から概算したトークン数を報告する
+	reportTokenUsage(ctx, estimateTokens(requestChars(req)), estimateTokens(utf8.RuneCountInString(content)))
+	return content, nil
+}
+
+// estimateTokens estimates the tokens of a text from its length
+func estimateTokens(chars int) int {
+	return (chars + 3) / 4
+}
+
+// requestChars returns the length of the messages of req
+func requestChars(req ChatRequest) int {
+	chars := 0
+	for _, m := range req.Messages {
+		chars += utf8.RuneCountInString(m.Content)
+	}
+	return chars
+}
+
+// NewLLMProvider creates the provider selected by cfg.Provider (openai, openai-compatible or stub).
+// An empty name selects openai.
+func NewLLMProvider(cfg LLMConfig) (LLMProvider, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, errors.New("OpenAI APIキーが設定されていません")
+		}
+		return NewOpenAIProvider(cfg), nil
+	case ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, errors.New("LLM_BASE_URL が設定されていません")
+		}
+		if cfg.Model == "" {
+			return nil, errors.New("LLM_MODEL が設定されていません")
+		}
+		return NewOpenAICompatibleProvider(cfg), nil
+	case ProviderStub:
+		return NewStubProvider(), nil
+	default:
+		return nil, fmt.Errorf("不明なLLMプロバイダーです: %s", cfg.Provider)
+	}
+}
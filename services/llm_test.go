@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// テスト用の正しい形式のロードマップ
+const validRoadmapJSON = `{"title": "ロードマップ", "summary": "要約",
+  "timeline": [{"title": "基礎学習", "duration_weeks": 4}],
+  "skills": [{"name": "Go", "priority": "high", "target_level": "intermediate"}],
+  "resources": []}`
+
+// recordingProvider は受け取ったリクエストを記録し、responses を順に返すテスト用のプロバイダーです
+type recordingProvider struct {
+	requests  []ChatRequest
+	responses []string
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Model() string { return "recording-model" }
+
+func (p *recordingProvider) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	p.requests = append(p.requests, req)
+	reportTokenUsage(ctx, 1000, 500)
+	if len(p.responses) == 0 {
+		return validRoadmapJSON, nil
+	}
+	response := p.responses[0]
+	if len(p.responses) > 1 {
+		p.responses = p.responses[1:]
+	}
+	return response, nil
+}
+
+func (p *recordingProvider) Stream(ctx context.Context, req ChatRequest, onToken func(string) error) (string, error) {
+	content, _ := p.Complete(ctx, req)
+	return content, onToken(content)
+}
+
+// TestRoadmapServiceUsesProvider はRoadmapServiceがプロバイダーにプロンプトを渡すことをテストします
+func TestRoadmapServiceUsesProvider(t *testing.T) {
+	provider := &recordingProvider{}
+	service := NewRoadmapService(provider)
+
+	job := &models.JobPosting{Title: "バックエンドエンジニア", Positions: []models.Position{{Name: "バックエンドエンジニア"}}}
+	response, err := service.GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{QuestionType: "skills"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, response.Roadmap, "# ロードマップ")
+	if assert.Len(t, provider.requests, 1) {
+		req := provider.requests[0]
+		assert.Equal(t, float32(0.5), req.Temperature)
+		assert.True(t, req.JSONMode)
+		assert.Len(t, req.Messages, 2)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Contains(t, req.Messages[0].Content, "バックエンド開発")
+		assert.Contains(t, req.Messages[0].Content, "duration_weeks")
+		assert.Contains(t, req.Messages[1].Content, "職種: バックエンドエンジニア")
+	}
+}
+
+// TestStubProviderIsDeterministic はスタブが同じ入力に同じ応答を返すことをテストします
+func TestStubProviderIsDeterministic(t *testing.T) {
+	service := NewRoadmapService(NewStubProvider())
+	job := &models.JobPosting{Title: "データエンジニア"}
+
+	first, err := service.GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{QuestionType: "general"})
+	assert.NoError(t, err)
+	second, err := service.GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{QuestionType: "general"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.Roadmap, second.Roadmap)
+	assert.Contains(t, first.Roadmap, "# データエンジニアのロードマップ")
+}
+
+// TestOpenAICompatibleProvider はOpenAI互換サーバーへのリクエストをテストします
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var received struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ローカルモデルの応答"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewLLMProvider(LLMConfig{Provider: ProviderOpenAICompatible, BaseURL: server.URL + "/v1/", Model: "llama3"})
+	assert.NoError(t, err)
+
+	content, err := provider.Complete(context.Background(), ChatRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "こんにちは"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ローカルモデルの応答", content)
+	assert.Equal(t, "llama3", received.Model)
+	assert.Equal(t, "こんにちは", received.Messages[0].Content)
+}
+
+// TestNewLLMProviderValidation は設定不足のプロバイダーがエラーになることをテストします
+func TestNewLLMProviderValidation(t *testing.T) {
+	_, err := NewLLMProvider(LLMConfig{Provider: ProviderOpenAI})
+	assert.Error(t, err)
+	_, err = NewLLMProvider(LLMConfig{Provider: ProviderOpenAICompatible, Model: "llama3"})
+	assert.Error(t, err)
+	_, err = NewLLMProvider(LLMConfig{Provider: "unknown", APIKey: "key"})
+	assert.Error(t, err)
+
+	provider, err := NewLLMProvider(LLMConfig{Provider: ProviderStub})
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderStub, provider.Name())
+}
+
+// TestOpenAICompatibleProviderStream はストリーミングとキャンセルをテストします
+func TestOpenAICompatibleProviderStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"こんにちは", "、世界"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", token)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewOpenAICompatibleProvider(LLMConfig{BaseURL: server.URL + "/v1", Model: "llama3"})
+	var tokens []string
+	content, err := provider.Stream(context.Background(), ChatRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "挨拶して"}},
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "こんにちは、世界", content)
+	assert.Equal(t, []string{"こんにちは", "、世界"}, tokens)
+}
+
+// TestStreamCancellation はコンテキストのキャンセルで上流のリクエストが中断されることをテストします
+func TestStreamCancellation(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"途中まで\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		// クライアントが切断するまで待つ
+		<-r.Context().Done()
+		close(upstreamDone)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider := NewOpenAICompatibleProvider(LLMConfig{BaseURL: server.URL + "/v1", Model: "llama3"})
+	_, err := provider.Stream(ctx, ChatRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "長い回答"}},
+	}, func(token string) error {
+		cancel()
+		return nil
+	})
+	assert.Error(t, err)
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("上流のリクエストがキャンセルされませんでした")
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 経験年数の上限
+const maxYearsOfExperience = 60
+
+// プロフィールの目標時期の形式
+const targetDateLayout = "2006-01-02"
+
+// CandidateProfile describes what a candidate already knows. A roadmap generated with a profile
+// focuses on the gap between the profile and the job's requirements.
+type CandidateProfile struct {
+	Skills            []CandidateSkill `json:"skills"`
+	YearsOfExperience float64          `json:"years_of_experience"`
+	WeeklyStudyHours  int              `json:"weekly_study_hours"` // 0 は指定なし
+	TargetDate        string           `json:"target_date"`        // YYYY-MM-DD。空は指定なし
+}
+
+// CandidateSkill is a skill the candidate already has
+type CandidateSkill struct {
+	Name  string `json:"name"`
+	Level string `json:"level"` // beginner, intermediate, advanced, expert
+}
+
+// Validate checks the profile. The target date must be after now.
+func (p *CandidateProfile) Validate(now time.Time) error {
+	var problems []string
+	for i, skill := range p.Skills {
+		if strings.TrimSpace(skill.Name) == "" {
+			problems = append(problems, fmt.Sprintf("skills[%d].name が空です", i))
+		}
+		if !containsString(skillLevels, skill.Level) {
+			problems = append(problems, fmt.Sprintf("skills[%d].level は %s のいずれかにしてください: %q", i, strings.Join(skillLevels, ", "), skill.Level))
+		}
+	}
+	if p.YearsOfExperience < 0 || p.YearsOfExperience > maxYearsOfExperience {
+		problems = append(problems, fmt.Sprintf("years_of_experience は0以上%d以下にしてください", maxYearsOfExperience))
+	}
+	if p.WeeklyStudyHours < 0 || p.WeeklyStudyHours > 7*24 {
+		problems = append(problems, "weekly_study_hours は0以上168以下にしてください")
+	}
+	if p.TargetDate != "" {
+		target, err := time.Parse(targetDateLayout, p.TargetDate)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("target_date は YYYY-MM-DD 形式で指定してください: %q", p.TargetDate))
+		} else if !target.After(now) {
+			problems = append(problems, "target_date は未来の日付を指定してください")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// WeeksUntilTarget returns the number of weeks from now until the target date, or 0 without a target date
+func (p *CandidateProfile) WeeksUntilTarget(now time.Time) int {
+	target, err := time.Parse(targetDateLayout, p.TargetDate)
+	if err != nil || !target.After(now) {
+		return 0
+	}
+	return int(math.Ceil(target.Sub(now).Hours() / (7 * 24)))
+}
+
+// Hash identifies the profile for caching at now. The order of the skills does not matter.
+// The weeks until the target date are part of the prompt, so the hash changes as the target date approaches.
+func (p *CandidateProfile) Hash(now time.Time) string {
+	skills := make([]string, 0, len(p.Skills))
+	for _, skill := range p.Skills {
+		skills = append(skills, strings.ToLower(strings.TrimSpace(skill.Name))+":"+skill.Level)
+	}
+	sort.Strings(skills)
+	return hashParts(
+		strings.Join(skills, ","),
+		strconv.FormatFloat(p.YearsOfExperience, 'f', -1, 64),
+		strconv.Itoa(p.WeeklyStudyHours),
+		p.TargetDate,
+		strconv.Itoa(p.WeeksUntilTarget(now)),
+	)
+}
+
+// profileData is the profile passed to the templates
+type profileData struct {
+	Skills            []CandidateSkill
+	YearsOfExperience float64
+	WeeklyStudyHours  int
+	TargetDate        string
+	WeeksUntilTarget  int
+}
+
+func newProfileData(p *CandidateProfile, now time.Time) *profileData {
+	if p == nil {
+		return nil
+	}
+	return &profileData{
+		Skills:            p.Skills,
+		YearsOfExperience: p.YearsOfExperience,
+		WeeklyStudyHours:  p.WeeklyStudyHours,
+		TargetDate:        p.TargetDate,
+		WeeksUntilTarget:  p.WeeksUntilTarget(now),
+	}
+}
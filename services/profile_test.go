@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// TestCandidateProfileValidate はプロフィールの検証をテストします
+func TestCandidateProfileValidate(t *testing.T) {
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	valid := CandidateProfile{
+		Skills:            []CandidateSkill{{Name: "Go", Level: "intermediate"}},
+		YearsOfExperience: 2.5,
+		WeeklyStudyHours:  10,
+		TargetDate:        "2025-07-31",
+	}
+	assert.NoError(t, valid.Validate(now))
+	assert.Equal(t, 13, valid.WeeksUntilTarget(now))
+
+	invalid := CandidateProfile{
+		Skills:            []CandidateSkill{{Name: "", Level: "guru"}},
+		YearsOfExperience: -1,
+		WeeklyStudyHours:  200,
+		TargetDate:        "2025-04-01",
+	}
+	err := invalid.Validate(now)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "skills[0].name が空です")
+		assert.Contains(t, err.Error(), "skills[0].level")
+		assert.Contains(t, err.Error(), "years_of_experience")
+		assert.Contains(t, err.Error(), "weekly_study_hours")
+		assert.Contains(t, err.Error(), "target_date は未来の日付")
+	}
+
+	// スキルの順序はハッシュに影響しない
+	a := CandidateProfile{Skills: []CandidateSkill{{Name: "Go", Level: "beginner"}, {Name: "SQL", Level: "advanced"}}}
+	b := CandidateProfile{Skills: []CandidateSkill{{Name: "sql", Level: "advanced"}, {Name: "Go", Level: "beginner"}}}
+	assert.Equal(t, a.Hash(now), b.Hash(now))
+	b.WeeklyStudyHours = 5
+	assert.NotEqual(t, a.Hash(now), b.Hash(now))
+
+	// 目標日までの週数が変わればハッシュも変わる
+	assert.Equal(t, valid.Hash(now), valid.Hash(now.Add(24*time.Hour)))
+	assert.NotEqual(t, valid.Hash(now), valid.Hash(now.AddDate(0, 0, 7)))
+}
+
+// TestPersonalizedRoadmap はプロフィールをプロンプトに含め、スキルギャップを必須にすることをテストします
+func TestPersonalizedRoadmap(t *testing.T) {
+	job := &models.JobPosting{Title: "バックエンドエンジニア", Requirements: "Goの実務経験"}
+	profile := &CandidateProfile{
+		Skills:            []CandidateSkill{{Name: "Python", Level: "advanced"}},
+		YearsOfExperience: 3,
+		WeeklyStudyHours:  8,
+	}
+
+	// スキルギャップのない回答は修正を依頼する
+	withGaps := `{"title": "ロードマップ", "timeline": [{"title": "Go入門", "duration_weeks": 4}],
+	  "skills": [{"name": "Go", "priority": "high", "target_level": "intermediate"}],
+	  "skill_gaps": [{"skill": "Go", "current_level": "none", "required_level": "intermediate", "weeks": 8, "advice": "Pythonの経験を活かす"}]}`
+	provider := &recordingProvider{responses: []string{validRoadmapJSON, withGaps}}
+	response, err := NewRoadmapService(provider).GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{Profile: profile})
+	assert.NoError(t, err)
+	if assert.Len(t, provider.requests, 2) {
+		system := provider.requests[0].Messages[0].Content
+		user := provider.requests[0].Messages[1].Content
+		assert.Contains(t, system, `"skill_gaps"`)
+		assert.Contains(t, user, "現在のスキル: Python（advanced）")
+		assert.Contains(t, user, "1週間に学習できる時間: 8時間")
+		assert.Contains(t, user, "スキルギャップ")
+		assert.Contains(t, provider.requests[1].Messages[3].Content, "skill_gaps が空です")
+	}
+	assert.Equal(t, "none", response.SkillGaps[0].CurrentLevel)
+	assert.Contains(t, response.Roadmap, "## スキルギャップ\n- **Go**: none → intermediate（約8週間）: Pythonの経験を活かす")
+
+	// プロフィールがない場合はスキルギャップを求めない
+	provider = &recordingProvider{}
+	_, err = NewRoadmapService(provider).GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, provider.requests[0].Messages[0].Content, "skill_gaps")
+
+	// プロフィールごとに別のキャッシュになる
+	plain, _ := ResolvePrompt(RoadmapOptions{})
+	personalized, _ := ResolvePrompt(RoadmapOptions{Profile: profile})
+	assert.NotEqual(t, RoadmapCacheKey(job, plain, GenerationParams{Model: "model"}), RoadmapCacheKey(job, personalized, GenerationParams{Model: "model"}))
+}
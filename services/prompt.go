@@ -0,0 +1,617 @@
+package services
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"howtv-server/models"
+)
+
+// prompts 配下のテンプレートはバイナリに埋め込まれ、PROMPT_DIR のファイルで上書きできる。
+// 言語ごとのディレクトリに次のファイルを置く。言語を追加する場合はディレクトリを追加するだけでよい。
+//
+//	<言語>/partials.tmpl               共通部品（job_info, position_expertise, format, repair）
+//	<言語>/system/<質問タイプ>.tmpl      システムプロンプト
+//	<言語>/user/<質問タイプ>/<名前>.tmpl  ユーザープロンプト。ID は "<質問タイプ>/<名前>"
+//	<言語>/markdown.tmpl               ロードマップのマークダウン表示
+//	<言語>/messages.json               APIのエラーメッセージ
+//
+// 各テンプレートの先頭には {{- /* version: 1 */ -}} の形式でバージョンを書く。
+//
+//go:embed prompts
+var embeddedPrompts embed.FS
+
+const (
+	promptPartialsFile = "partials.tmpl"
+	promptMarkdownFile = "markdown.tmpl"
+	promptMessagesFile = "messages.json"
+	promptSystemDir    = "system"
+	promptUserDir      = "user"
+	promptExt          = ".tmpl"
+
+	// DefaultPromptTemplate is the template name used when neither a template nor a seed is given
+	DefaultPromptTemplate = "default"
+	// DefaultQuestionType is used for question types without their own templates
+	DefaultQuestionType = "general"
+	// DefaultLanguage is used when no language is requested or none of the requested ones is available
+	DefaultLanguage = "ja"
+)
+
+// ErrUnknownPromptTemplate is returned when the requested template does not exist for the question type
+var ErrUnknownPromptTemplate = errors.New("unknown prompt template")
+
+// ErrUnsupportedLanguage is returned when there are no templates for the requested language
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+var promptVersionPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// PromptTemplate is a versioned prompt template file
+type PromptTemplate struct {
+	ID      string
+	Version string
+	tmpl    *template.Template
+}
+
+// Ref identifies the template and its version, e.g. "general/default@1"
+func (t *PromptTemplate) Ref() string {
+	return t.ID + "@" + t.Version
+}
+
+// PromptSet is the set of templates of one language
+type PromptSet struct {
+	Lang            string
+	partialsVersion string
+	partials        *template.Template
+	system          map[string]*PromptTemplate
+	user            map[string][]*PromptTemplate // 質問タイプごとにID順
+	markdown        *template.Template
+	messages        map[string]string
+}
+
+// PromptCatalog holds the prompt sets of all languages
+type PromptCatalog struct {
+	sets      map[string]*PromptSet
+	languages []string // 先頭は既定の言語
+	matcher   language.Matcher
+}
+
+// ResolvedPrompt is the pair of templates chosen for a roadmap request
+type ResolvedPrompt struct {
+	QuestionType string
+	System       *PromptTemplate
+	User         *PromptTemplate
+	Profile      *CandidateProfile
+	set          *PromptSet
+}
+
+// Lang returns the language of the templates
+func (p *ResolvedPrompt) Lang() string {
+	return p.set.Lang
+}
+
+// Version identifies all templates that produce the prompt. It changes whenever one of them is edited.
+func (p *ResolvedPrompt) Version() string {
+	return p.set.Lang + ":" + p.System.Ref() + " " + p.User.Ref() + " partials@" + p.set.partialsVersion
+}
+
+// promptData is the data passed to the templates
+type promptData struct {
+	QuestionType   string
+	Title          string
+	Positions      string
+	Description    string
+	Requirements   string
+	Location       string
+	EmploymentType string
+	Keywords       []string
+	PositionFocus  []string
+	Profile        *profileData
+}
+
+// Render renders the system and user prompts for the job
+func (p *ResolvedPrompt) Render(job *models.JobPosting) (string, string, error) {
+	var positionNames []string
+	for _, pos := range job.Positions {
+		positionNames = append(positionNames, pos.Name)
+	}
+	data := promptData{
+		QuestionType:   p.QuestionType,
+		Title:          job.Title,
+		Positions:      strings.Join(positionNames, ", "),
+		Description:    job.Description,
+		Requirements:   job.Requirements,
+		Location:       job.Location,
+		EmploymentType: job.EmploymentType,
+		Keywords:       extractKeywords(job),
+		PositionFocus:  positionFocus(job),
+		Profile:        newProfileData(p.Profile, time.Now()),
+	}
+
+	system, err := executePrompt(p.System, data)
+	if err != nil {
+		return "", "", err
+	}
+	user, err := executePrompt(p.User, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	// プロフィールがある場合は求人との差に焦点を当てるよう指示を足す
+	if p.Profile != nil {
+		var b strings.Builder
+		if err := p.set.partials.ExecuteTemplate(&b, "profile", data); err != nil {
+			return "", "", fmt.Errorf("プロフィールのプロンプトの生成に失敗しました: %w", err)
+		}
+		user += "\n\n" + strings.TrimSpace(b.String())
+	}
+	return system, user, nil
+}
+
+// parseResponse validates the model output. A personalized roadmap must include the skill gaps.
+func (p *ResolvedPrompt) parseResponse(content string) (*RoadmapResponse, error) {
+	roadmap, err := parseRoadmapResponse(content)
+	if err != nil {
+		return nil, err
+	}
+	if p.Profile != nil && len(roadmap.SkillGaps) == 0 {
+		return nil, &RoadmapValidationError{Problems: []string{"skill_gaps が空です"}}
+	}
+	return roadmap, nil
+}
+
+// RepairPrompt asks the model to fix the problems found in its previous answer
+func (p *ResolvedPrompt) RepairPrompt(err error) (string, error) {
+	var problems []string
+	if validationErr, ok := err.(*RoadmapValidationError); ok {
+		problems = validationErr.Problems
+	} else {
+		problems = []string{err.Error()}
+	}
+
+	var b strings.Builder
+	if err := p.set.partials.ExecuteTemplate(&b, "repair", struct{ Problems []string }{problems}); err != nil {
+		return "", fmt.Errorf("修正依頼のプロンプトの生成に失敗しました: %w", err)
+	}
+	return b.String(), nil
+}
+
+// RenderMarkdown renders the structured roadmap as markdown in the language of the set
+func (s *PromptSet) RenderMarkdown(roadmap *RoadmapResponse) (string, error) {
+	var b strings.Builder
+	if err := s.markdown.Execute(&b, roadmap); err != nil {
+		return "", fmt.Errorf("ロードマップのマークダウン表示に失敗しました: %w", err)
+	}
+	return b.String(), nil
+}
+
+func executePrompt(t *PromptTemplate, data promptData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("プロンプト %s の生成に失敗しました: %w", t.ID, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// 職種分類のノード（職種またはカテゴリ）ごとの専門分野。下位のノードほど優先する
+var positionFocusByName = map[string]string{
+	"フロントエンドエンジニア": "frontend",
+	"バックエンドエンジニア":  "backend",
+	"フルスタックエンジニア":  "fullstack",
+	"AI/ML エンジニア":  "ai",
+	"データ・AI":       "data",
+	"インフラ・クラウド":    "devops",
+}
+
+// 分類の上記以外のノードは名前の単語で専門分野を判定する
+var positionFocusByWord = map[string]string{
+	"frontend": "frontend", "backend": "backend", "fullstack": "fullstack",
+	"data": "data", "ai": "ai", "ml": "ai",
+	"devops": "devops", "sre": "devops", "infra": "devops", "infrastructure": "devops",
+}
+
+// 日本語の名前は単語に区切れないため語句を含むかで判定する
+var positionFocusByPhrase = []struct{ phrase, focus string }{
+	{"フロントエンド", "frontend"},
+	{"バックエンド", "backend"},
+	{"フルスタック", "fullstack"},
+	{"データ", "data"},
+	{"機械学習", "ai"},
+	{"インフラ", "devops"},
+}
+
+var positionWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// 職種に応じてシステムプロンプトで強調する専門分野
+func positionFocus(job *models.JobPosting) []string {
+	var focus []string
+	seen := map[string]bool{}
+	for _, pos := range job.Positions {
+		if f := focusOfPosition(&pos); f != "" && !seen[f] {
+			seen[f] = true
+			focus = append(focus, f)
+		}
+	}
+	return focus
+}
+
+// focusOfPosition walks from the position up its stored parent chain and returns the focus of the nearest
+// node that has one. Positions loaded without LoadPositionParents are judged by themselves only.
+func focusOfPosition(pos *models.Position) string {
+	for node := pos; node != nil; node = node.Parent {
+		if f, ok := positionFocusByName[node.Name]; ok {
+			return f
+		}
+		for _, term := range node.Terms() {
+			for _, word := range positionWordPattern.FindAllString(term, -1) {
+				if f, ok := positionFocusByWord[word]; ok {
+					return f
+				}
+			}
+			for _, p := range positionFocusByPhrase {
+				if strings.Contains(term, p.phrase) {
+					return p.focus
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Resolve chooses the templates for the request.
+// TemplateID selects a template by ID ("skills/recruiter" or just "recruiter"), otherwise Seed selects one
+// deterministically from the templates of the question type, otherwise the default template is used.
+func (s *PromptSet) Resolve(opts RoadmapOptions) (*ResolvedPrompt, error) {
+	questionType := opts.QuestionType
+	if _, ok := s.system[questionType]; !ok {
+		questionType = DefaultQuestionType
+	}
+	templates := s.user[questionType]
+
+	var selected *PromptTemplate
+	switch {
+	case opts.TemplateID != "":
+		id := opts.TemplateID
+		if !strings.Contains(id, "/") {
+			id = questionType + "/" + id
+		}
+		for _, t := range templates {
+			if t.ID == id {
+				selected = t
+			}
+		}
+		if selected == nil {
+			return nil, fmt.Errorf("%w: 質問タイプ %s にテンプレート %s はありません", ErrUnknownPromptTemplate, questionType, opts.TemplateID)
+		}
+	case opts.Seed != nil:
+		n := int64(len(templates))
+		selected = templates[((*opts.Seed%n)+n)%n]
+	default:
+		for _, t := range templates {
+			if t.ID == questionType+"/"+DefaultPromptTemplate {
+				selected = t
+			}
+		}
+		if selected == nil {
+			selected = templates[0]
+		}
+	}
+
+	return &ResolvedPrompt{
+		QuestionType: questionType,
+		System:       s.system[questionType],
+		User:         selected,
+		Profile:      opts.Profile,
+		set:          s,
+	}, nil
+}
+
+// Templates returns the IDs of the user prompt templates of a question type
+func (s *PromptSet) Templates(questionType string) []string {
+	var ids []string
+	for _, t := range s.user[questionType] {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+// Set returns the prompt set of a language. An empty lang selects the default language.
+func (c *PromptCatalog) Set(lang string) (*PromptSet, error) {
+	if lang == "" {
+		lang = c.languages[0]
+	}
+	set, ok := c.sets[lang]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, lang)
+	}
+	return set, nil
+}
+
+// Resolve chooses the templates for the request in the language given by opts.Lang
+func (c *PromptCatalog) Resolve(opts RoadmapOptions) (*ResolvedPrompt, error) {
+	set, err := c.Set(opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	return set.Resolve(opts)
+}
+
+// Languages returns the available languages, the default one first
+func (c *PromptCatalog) Languages() []string {
+	return c.languages
+}
+
+// NegotiateLanguage returns the available language that best matches an Accept-Language header
+func (c *PromptCatalog) NegotiateLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return c.languages[0]
+	}
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return c.languages[0]
+	}
+	return c.languages[index]
+}
+
+// Message returns a localized message. Messages missing in lang fall back to the default language.
+// args are formatted into the message with fmt.Sprintf.
+func (c *PromptCatalog) Message(lang, key string, args ...interface{}) string {
+	format, ok := "", false
+	if set, found := c.sets[lang]; found {
+		format, ok = set.messages[key]
+	}
+	if !ok {
+		if format, ok = c.sets[c.languages[0]].messages[key]; !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// LoadPromptCatalog loads the embedded templates. Files in dir, if not empty, replace the embedded files
+// with the same path and may add templates and languages.
+func LoadPromptCatalog(dir string) (*PromptCatalog, error) {
+	embedded, err := fs.Sub(embeddedPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	files, err := readPromptFiles(embedded, nil)
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if files, err = readPromptFiles(os.DirFS(dir), files); err != nil {
+			return nil, fmt.Errorf("プロンプトディレクトリ %s の読み込みに失敗しました: %w", dir, err)
+		}
+	}
+
+	// 最上位のディレクトリ名が言語になる
+	byLang := map[string]map[string]string{}
+	for name, content := range files {
+		lang, rest, ok := strings.Cut(name, "/")
+		if !ok {
+			continue
+		}
+		if byLang[lang] == nil {
+			byLang[lang] = map[string]string{}
+		}
+		byLang[lang][rest] = content
+	}
+	if _, ok := byLang[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("既定の言語 %s のテンプレートがありません", DefaultLanguage)
+	}
+
+	catalog := &PromptCatalog{sets: map[string]*PromptSet{}, languages: []string{DefaultLanguage}}
+	for lang := range byLang {
+		if lang != DefaultLanguage {
+			catalog.languages = append(catalog.languages, lang)
+		}
+	}
+	sort.Strings(catalog.languages[1:])
+
+	var tags []language.Tag
+	for _, lang := range catalog.languages {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("言語 %s のディレクトリ名が不正です: %w", lang, err)
+		}
+		set, err := parsePromptSet(lang, byLang[lang])
+		if err != nil {
+			return nil, fmt.Errorf("言語 %s: %w", lang, err)
+		}
+		catalog.sets[lang] = set
+		tags = append(tags, tag)
+	}
+	catalog.matcher = language.NewMatcher(tags)
+	return catalog, nil
+}
+
+func readPromptFiles(fsys fs.FS, files map[string]string) (map[string]string, error) {
+	if files == nil {
+		files = map[string]string{}
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || (path.Ext(name) != promptExt && path.Base(name) != promptMessagesFile) {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = string(content)
+		return nil
+	})
+	return files, err
+}
+
+func parsePromptSet(lang string, files map[string]string) (*PromptSet, error) {
+	partials, ok := files[promptPartialsFile]
+	if !ok {
+		return nil, fmt.Errorf("%s がありません", promptPartialsFile)
+	}
+	partialsVersion, err := promptVersion(promptPartialsFile, partials)
+	if err != nil {
+		return nil, err
+	}
+	base, err := template.New(promptPartialsFile).Funcs(template.FuncMap{"join": strings.Join}).Parse(partials)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &PromptSet{
+		Lang:            lang,
+		partialsVersion: partialsVersion,
+		partials:        base,
+		system:          map[string]*PromptTemplate{},
+		user:            map[string][]*PromptTemplate{},
+		messages:        map[string]string{},
+	}
+
+	markdown, ok := files[promptMarkdownFile]
+	if !ok {
+		return nil, fmt.Errorf("%s がありません", promptMarkdownFile)
+	}
+	set.markdown, err = template.New(promptMarkdownFile).
+		Funcs(template.FuncMap{"inc": func(i int) int { return i + 1 }}).Parse(markdown)
+	if err != nil {
+		return nil, err
+	}
+
+	if messages, ok := files[promptMessagesFile]; ok {
+		if err := json.Unmarshal([]byte(messages), &set.messages); err != nil {
+			return nil, fmt.Errorf("%s を解析できません: %w", promptMessagesFile, err)
+		}
+	}
+
+	for name, content := range files {
+		dir, file := path.Split(name)
+		id := strings.TrimSuffix(file, promptExt)
+
+		var questionType string
+		switch {
+		case path.Ext(file) != promptExt:
+			continue
+		case dir == promptSystemDir+"/":
+			questionType = id
+		case strings.HasPrefix(dir, promptUserDir+"/") && strings.Count(dir, "/") == 2:
+			questionType = strings.Trim(strings.TrimPrefix(dir, promptUserDir+"/"), "/")
+			id = questionType + "/" + id
+		default:
+			continue
+		}
+
+		version, err := promptVersion(name, content)
+		if err != nil {
+			return nil, err
+		}
+		clone, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := clone.New(name).Parse(content)
+		if err != nil {
+			return nil, err
+		}
+
+		t := &PromptTemplate{ID: id, Version: version, tmpl: tmpl}
+		if dir == promptSystemDir+"/" {
+			set.system[questionType] = t
+		} else {
+			set.user[questionType] = append(set.user[questionType], t)
+		}
+	}
+
+	if _, ok := set.system[DefaultQuestionType]; !ok {
+		return nil, fmt.Errorf("%s/%s%s がありません", promptSystemDir, DefaultQuestionType, promptExt)
+	}
+	for questionType := range set.system {
+		templates := set.user[questionType]
+		if len(templates) == 0 {
+			return nil, fmt.Errorf("質問タイプ %s のユーザープロンプトがありません", questionType)
+		}
+		sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	}
+	return set, nil
+}
+
+func promptVersion(name, content string) (string, error) {
+	m := promptVersionPattern.FindStringSubmatch(content)
+	if m == nil {
+		return "", fmt.Errorf("%s の先頭にバージョンがありません", name)
+	}
+	return m[1], nil
+}
+
+// prompts is the template catalog used to build roadmap requests
+var prompts = mustLoadPromptCatalog()
+
+func mustLoadPromptCatalog() *PromptCatalog {
+	catalog, err := LoadPromptCatalog("")
+	if err != nil {
+		panic("埋め込みのプロンプトテンプレートが不正です: " + err.Error())
+	}
+	return catalog
+}
+
+// UsePromptDir replaces the prompt templates with the embedded ones overridden by the files in dir.
+// It should be called at startup before roadmaps are generated.
+func UsePromptDir(dir string) error {
+	catalog, err := LoadPromptCatalog(dir)
+	if err != nil {
+		return err
+	}
+	prompts = catalog
+	return nil
+}
+
+// ResolvePrompt chooses the prompt templates for the request
+func ResolvePrompt(opts RoadmapOptions) (*ResolvedPrompt, error) {
+	return prompts.Resolve(opts)
+}
+
+// Languages returns the languages roadmaps can be generated in, the default one first
+func Languages() []string {
+	return prompts.Languages()
+}
+
+// SupportedLanguage reports whether roadmaps can be generated in lang
+func SupportedLanguage(lang string) bool {
+	_, err := prompts.Set(lang)
+	return err == nil
+}
+
+// NegotiateLanguage returns the language that best matches an Accept-Language header
+func NegotiateLanguage(acceptLanguage string) string {
+	return prompts.NegotiateLanguage(acceptLanguage)
+}
+
+// Message returns a localized API message
+func Message(lang, key string, args ...interface{}) string {
+	return prompts.Message(lang, key, args...)
+}
+
+// renderDefaultMarkdown renders a roadmap as markdown in the default language
+func renderDefaultMarkdown(roadmap *RoadmapResponse) string {
+	set, _ := prompts.Set(DefaultLanguage)
+	markdown, err := set.RenderMarkdown(roadmap)
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	return markdown
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// TestResolvePrompt はテンプレートの選択が決定的であることをテストします
+func TestResolvePrompt(t *testing.T) {
+	// 既定のテンプレート
+	prompt, err := ResolvePrompt(RoadmapOptions{QuestionType: "skills"})
+	assert.NoError(t, err)
+	assert.Equal(t, "skills/default", prompt.User.ID)
+	assert.Equal(t, "1", prompt.User.Version)
+	assert.Equal(t, "skills", prompt.System.ID)
+
+	// IDで指定（質問タイプを省略した名前でもよい）
+	prompt, err = ResolvePrompt(RoadmapOptions{QuestionType: "skills", TemplateID: "skills/recruiter"})
+	assert.NoError(t, err)
+	assert.Equal(t, "skills/recruiter", prompt.User.ID)
+	prompt, err = ResolvePrompt(RoadmapOptions{QuestionType: "skills", TemplateID: "recruiter"})
+	assert.NoError(t, err)
+	assert.Equal(t, "skills/recruiter", prompt.User.ID)
+
+	// 同じseedは常に同じテンプレートを選び、すべてのテンプレートに届く
+	selected := map[string]bool{}
+	for seed := int64(-3); seed < 3; seed++ {
+		s := seed
+		first, err := ResolvePrompt(RoadmapOptions{QuestionType: "career", Seed: &s})
+		assert.NoError(t, err)
+		second, _ := ResolvePrompt(RoadmapOptions{QuestionType: "career", Seed: &s})
+		assert.Equal(t, first.User.ID, second.User.ID)
+		selected[first.User.ID] = true
+	}
+	set, _ := prompts.Set(DefaultLanguage)
+	assert.Len(t, selected, len(set.Templates("career")))
+
+	// 未知の質問タイプは general として扱う
+	prompt, err = ResolvePrompt(RoadmapOptions{QuestionType: "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, "general/default", prompt.User.ID)
+
+	// 他の質問タイプのテンプレートは指定できない
+	_, err = ResolvePrompt(RoadmapOptions{QuestionType: "skills", TemplateID: "career/options"})
+	assert.True(t, errors.Is(err, ErrUnknownPromptTemplate))
+}
+
+// TestLoadPromptCatalogOverride はディスク上のファイルで埋め込みのテンプレートを上書き・追加できることをテストします
+func TestLoadPromptCatalogOverride(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "ja", "user", "general"), 0o755)
+	os.WriteFile(filepath.Join(dir, "ja", "user", "general", "default.tmpl"),
+		[]byte("{{- /* version: 2 */ -}}\n{{.Title}}のロードマップを作ってください:{{template \"job_info\" .}}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "ja", "user", "general", "short.tmpl"),
+		[]byte("{{- /* version: 1 */ -}}\n{{.Title}}について短く答えてください"), 0o644)
+
+	catalog, err := LoadPromptCatalog(dir)
+	assert.NoError(t, err)
+	set, _ := catalog.Set("ja")
+	assert.Contains(t, set.Templates("general"), "general/short")
+
+	job := &models.JobPosting{Title: "Goエンジニア", Location: "東京都"}
+	prompt, err := catalog.Resolve(RoadmapOptions{QuestionType: "general"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", prompt.User.Version)
+	system, user, err := prompt.Render(job)
+	assert.NoError(t, err)
+	assert.Contains(t, system, "duration_weeks")
+	assert.Contains(t, user, "Goエンジニアのロードマップを作ってください")
+	assert.Contains(t, user, "勤務地: 東京都")
+
+	// バージョンが変わるとキャッシュキーも変わる
+	embedded, _ := ResolvePrompt(RoadmapOptions{QuestionType: "general"})
+	assert.NotEqual(t, RoadmapCacheKey(job, embedded, GenerationParams{Model: "model"}), RoadmapCacheKey(job, prompt, GenerationParams{Model: "model"}))
+
+	// バージョンのないテンプレートは読み込めない
+	os.WriteFile(filepath.Join(dir, "ja", "user", "general", "broken.tmpl"), []byte("{{.Title}}"), 0o644)
+	_, err = LoadPromptCatalog(dir)
+	assert.Error(t, err)
+}
+
+// TestPromptLanguages は言語ごとのテンプレートとメッセージをテストします
+func TestPromptLanguages(t *testing.T) {
+	assert.Equal(t, []string{"ja", "en"}, Languages())
+
+	job := &models.JobPosting{Title: "Backend Engineer", Location: "Remote", Positions: []models.Position{{Name: "Backend Engineer"}}}
+	prompt, err := ResolvePrompt(RoadmapOptions{QuestionType: "skills", Lang: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "en", prompt.Lang())
+	system, user, err := prompt.Render(job)
+	assert.NoError(t, err)
+	assert.Contains(t, system, "backend development")
+	assert.Contains(t, user, "Job title: Backend Engineer")
+	assert.Contains(t, user, "Location: Remote")
+
+	// 同じテンプレートIDでも言語が違えばキャッシュキーは異なる
+	ja, _ := ResolvePrompt(RoadmapOptions{QuestionType: "skills"})
+	assert.Equal(t, prompt.User.ID, ja.User.ID)
+	assert.NotEqual(t, RoadmapCacheKey(job, ja, GenerationParams{Model: "model"}), RoadmapCacheKey(job, prompt, GenerationParams{Model: "model"}))
+
+	_, err = ResolvePrompt(RoadmapOptions{Lang: "fr"})
+	assert.True(t, errors.Is(err, ErrUnsupportedLanguage))
+
+	// Accept-Language から最も近い言語を選ぶ
+	assert.Equal(t, "en", NegotiateLanguage("en-US,en;q=0.9"))
+	assert.Equal(t, "en", NegotiateLanguage("fr-FR, en;q=0.5, ja;q=0.1"))
+	assert.Equal(t, "ja", NegotiateLanguage("ja-JP"))
+	assert.Equal(t, "ja", NegotiateLanguage("fr"))
+	assert.Equal(t, "ja", NegotiateLanguage(""))
+
+	assert.Equal(t, "Job posting not found", Message("en", "job_not_found"))
+	assert.Equal(t, "seed は整数で指定してください: x", Message("ja", "invalid_seed", "x"))
+}
+
+// TestRenderMarkdownLanguages はマークダウン表示が言語ごとのテンプレートを使うことをテストします
+func TestRenderMarkdownLanguages(t *testing.T) {
+	roadmap, err := parseRoadmapResponse(validRoadmapJSON)
+	assert.NoError(t, err)
+
+	en, _ := prompts.Set("en")
+	markdown, err := en.RenderMarkdown(roadmap)
+	assert.NoError(t, err)
+	assert.Contains(t, markdown, "## Learning timeline (4 weeks in total)")
+	assert.Contains(t, markdown, "1. **基礎学習** (4 weeks)")
+	assert.Contains(t, roadmap.RenderMarkdown(), "## 学習タイムライン（合計4週間）")
+
+	// 英語で生成すると英語のマークダウンになり、修正依頼も英語になる
+	provider := &recordingProvider{responses: []string{`{"title": "t", "timeline": []}`, validRoadmapJSON}}
+	response, err := NewRoadmapService(provider).GenerateCareerRoadmap(context.Background(), &models.JobPosting{Title: "Engineer"}, RoadmapOptions{Lang: "en"})
+	assert.NoError(t, err)
+	assert.Contains(t, response.Roadmap, "## Required skills")
+	if assert.Len(t, provider.requests, 2) {
+		assert.Contains(t, provider.requests[1].Messages[3].Content, "Your previous answer does not match the required format")
+	}
+}
+
+// TestPositionFocus は保存された職種の階層と単語単位の判定による専門分野をテストします
+func TestPositionFocus(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("テスト用データベースの接続に失敗しました: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("マイグレーションに失敗しました: %v", err)
+	}
+
+	// APIで追加・移動された職種（既定の分類にはない）
+	var backend models.Position
+	db.Where("name = ?", "バックエンドエンジニア").First(&backend)
+	for _, name := range []string{"Platform Engineer", "Senior Data Analyst", "Email Marketing Manager", "HTML Coder", "Full-time Sales", "Maintenance Staff"} {
+		position := models.Position{Name: name}
+		if name == "Platform Engineer" {
+			position.ParentID = &backend.ID
+		}
+		db.Create(&position)
+	}
+
+	focus := func(names ...string) []string {
+		var positions []models.Position
+		db.Where("name IN ?", names).Order("id").Find(&positions)
+		if !assert.Len(t, positions, len(names)) {
+			return nil
+		}
+		assert.NoError(t, models.LoadPositionParents(db, positions))
+		return positionFocus(&models.JobPosting{Positions: positions})
+	}
+
+	assert.Equal(t, []string{"backend"}, focus("バックエンドエンジニア"))
+	// 上位の職種やカテゴリから決まる
+	assert.Equal(t, []string{"backend"}, focus("Platform Engineer"))
+	assert.Equal(t, []string{"devops"}, focus("クラウドエンジニア"))
+	assert.Equal(t, []string{"ai"}, focus("AI/ML エンジニア"))
+	assert.Equal(t, []string{"data"}, focus("データエンジニア", "データベースエンジニア"))
+	assert.Nil(t, focus("QAエンジニア"))
+	// 分類に専門分野がない職種は単語で判定する
+	assert.Equal(t, []string{"data"}, focus("Senior Data Analyst"))
+	assert.Equal(t, []string{"devops"}, positionFocus(&models.JobPosting{Positions: []models.Position{{Name: "Site Engineer", Aliases: []models.PositionAlias{{Name: "SRE"}}}}}))
+	// 単語の一部には反応しない
+	for _, name := range []string{"Email Marketing Manager", "HTML Coder", "Full-time Sales", "Maintenance Staff"} {
+		assert.Nil(t, focus(name), name)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLM呼び出しの失敗の種類（コントローラーでHTTPステータスに対応付ける）
+var (
+	ErrLLMTimeout     = errors.New("LLMの応答がタイムアウトしました")
+	ErrLLMRateLimited = errors.New("LLMのレート制限を超えました")
+	ErrLLMUnavailable = errors.New("LLMに接続できません")
+)
+
+// ロードマップ生成の失敗を表すエラーコード
+const (
+	ErrorCodeLLMTimeout         = "llm_timeout"
+	ErrorCodeLLMRateLimited     = "llm_rate_limited"
+	ErrorCodeLLMUnavailable     = "llm_unavailable"
+	ErrorCodeLLMInvalidResponse = "llm_invalid_response"
+	ErrorCodeGenerationFailed   = "roadmap_generation_failed"
+	ErrorCodeUnknownTemplate    = "unknown_prompt_template"
+	ErrorCodeBudgetExceeded     = "llm_budget_exceeded"
+	ErrorCodeModelNotAllowed    = "model_not_allowed"
+)
+
+// RoadmapErrorCode returns the error code of a roadmap generation error
+func RoadmapErrorCode(err error) string {
+	var validationErr *RoadmapValidationError
+	switch {
+	case errors.Is(err, ErrLLMTimeout):
+		return ErrorCodeLLMTimeout
+	case errors.Is(err, ErrLLMRateLimited):
+		return ErrorCodeLLMRateLimited
+	case errors.Is(err, ErrLLMUnavailable):
+		return ErrorCodeLLMUnavailable
+	case errors.As(err, &validationErr):
+		return ErrorCodeLLMInvalidResponse
+	case errors.Is(err, ErrUnknownPromptTemplate):
+		return ErrorCodeUnknownTemplate
+	case errors.Is(err, ErrLLMBudgetExceeded):
+		return ErrorCodeBudgetExceeded
+	case errors.Is(err, ErrModelNotAllowed):
+		return ErrorCodeModelNotAllowed
+	default:
+		return ErrorCodeGenerationFailed
+	}
+}
+
+// リトライの既定値
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// retryTransport retries requests that failed with a network error, 429 or 5xx.
+// It waits with exponential backoff and jitter, or for the duration given by Retry-After.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func newRetryTransport(maxRetries int, baseDelay time.Duration) *retryTransport {
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	return &retryTransport{
+		base:       http.DefaultTransport,
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+		maxDelay:   defaultRetryMaxDelay,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			// リトライではリクエストボディを作り直す
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.maxRetries || !shouldRetry(ctx, resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		// 待っている間にタイムアウトする場合はリトライしない
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrCallbackAddressNotAllowed)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the wait before the next attempt. Retry-After takes precedence over the exponential backoff.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, t.maxDelay)
+		}
+	}
+
+	delay := t.baseDelay << attempt
+	if delay <= 0 || delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	// 同時に失敗したリクエストが一斉に再送しないよう、半分から全体の間でばらつかせる
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// classifyLLMError wraps an upstream error with ErrLLMTimeout, ErrLLMRateLimited or ErrLLMUnavailable when it is one of them
+func classifyLLMError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrLLMTimeout, err)
+	}
+
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %v", ErrLLMRateLimited, err)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %v", ErrLLMUnavailable, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return fmt.Errorf("%w: %v", ErrLLMTimeout, err)
+		}
+		return fmt.Errorf("%w: %v", ErrLLMUnavailable, err)
+	}
+	return err
+}
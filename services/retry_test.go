@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const chatCompletionJSON = `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`
+
+// TestRetryOnRateLimit は429・5xxでリトライし、Retry-Afterに従うことをテストします
+func TestRetryOnRateLimit(t *testing.T) {
+	var calls int32
+	var retryAt time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			retryAt = time.Now().Add(time.Second)
+		case 2:
+			if time.Now().Before(retryAt) {
+				t.Errorf("Retry-Afterより前にリトライされました")
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, chatCompletionJSON)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewOpenAICompatibleProvider(LLMConfig{BaseURL: server.URL, Model: "m", MaxRetries: 3, RetryBaseDelay: time.Millisecond})
+	content, err := provider.Complete(context.Background(), ChatRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", content)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+// TestLLMErrorClassification は上流の失敗が種類ごとのエラーになることをテストします
+func TestLLMErrorClassification(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		delay    time.Duration
+		expected error
+	}{
+		{"レート制限", http.StatusTooManyRequests, 0, ErrLLMRateLimited},
+		{"サーバーエラー", http.StatusServiceUnavailable, 0, ErrLLMUnavailable},
+		{"タイムアウト", http.StatusOK, 200 * time.Millisecond, ErrLLMTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				select {
+				case <-time.After(tc.delay):
+				case <-r.Context().Done():
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, `{"error":{"message":"failed"}}`)
+			}))
+			defer server.Close()
+
+			provider := NewOpenAICompatibleProvider(LLMConfig{
+				BaseURL: server.URL, Model: "m", MaxRetries: 2,
+				RetryBaseDelay: time.Millisecond, Timeout: 50 * time.Millisecond,
+			})
+			_, err := provider.Complete(context.Background(), ChatRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}})
+
+			assert.True(t, errors.Is(err, tc.expected), "期待されるエラー %v に対して %v が返されました", tc.expected, err)
+			if tc.delay == 0 {
+				// リトライ回数を使い切ってから失敗する
+				assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+			}
+		})
+	}
+}
+
+// TestParseRetryAfter はRetry-Afterヘッダーの解釈をテストします
+func TestParseRetryAfter(t *testing.T) {
+	wait, ok := parseRetryAfter("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+
+	wait, ok = parseRetryAfter(time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, float64(10*time.Second), float64(wait), float64(2*time.Second))
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"howtv-server/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoadmapJobHash hashes the job fields that are fed into the prompt
+func RoadmapJobHash(job *models.JobPosting) string {
+	var positionNames []string
+	for _, pos := range job.Positions {
+		positionNames = append(positionNames, pos.Name)
+	}
+	return hashParts(
+		job.Title,
+		strings.Join(positionNames, ","),
+		job.Description,
+		job.Requirements,
+		job.Location,
+		job.EmploymentType,
+	)
+}
+
+// RoadmapCacheKey returns the cache key of a roadmap for the job content, question type, generation parameters, prompt templates
+// and candidate profile. Editing a template changes its version and therefore the key, so that cached roadmaps are regenerated.
+func RoadmapCacheKey(job *models.JobPosting, prompt *ResolvedPrompt, params GenerationParams) string {
+	return hashParts(RoadmapJobHash(job), prompt.QuestionType, params.Model,
+		strconv.FormatFloat(float64(params.Temperature), 'g', -1, 32),
+		strconv.Itoa(params.MaxTokens),
+		strconv.FormatFloat(float64(params.TopP), 'g', -1, 32),
+		prompt.Version(), profileHash(prompt.Profile))
+}
+
+func profileHash(profile *CandidateProfile) string {
+	if profile == nil {
+		return ""
+	}
+	return profile.Hash(time.Now())
+}
+
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		// 区切りを入れて "ab"+"c" と "a"+"bc" を区別する
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetOrGenerateRoadmap returns the cached roadmap for the job or generates and stores a new one.
+// When refresh is true the cache is bypassed and overwritten, unless the monthly LLM budget is exceeded.
+// The bool result reports a cache hit.
+func GetOrGenerateRoadmap(ctx context.Context, db *gorm.DB, generator RoadmapGenerator, job *models.JobPosting, opts RoadmapOptions, refresh bool) (*models.Roadmap, bool, error) {
+	if !refresh {
+		cached, err := FindCachedRoadmap(db, generator, job, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		if cached != nil {
+			return cached, true, nil
+		}
+	}
+
+	response, err := generator.GenerateCareerRoadmap(ctx, job, opts)
+	if errors.Is(err, ErrLLMBudgetExceeded) && refresh {
+		// 予算を超えた場合は再生成せずキャッシュを返す
+		if cached, findErr := FindCachedRoadmap(db, generator, job, opts); findErr == nil && cached != nil {
+			return cached, true, nil
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	roadmap, err := SaveRoadmap(db, generator, job, opts, response)
+	return roadmap, false, err
+}
+
+// FindCachedRoadmap returns the cached roadmap for the job, or nil if there is none
+func FindCachedRoadmap(db *gorm.DB, generator RoadmapGenerator, job *models.JobPosting, opts RoadmapOptions) (*models.Roadmap, error) {
+	prompt, err := ResolvePrompt(opts)
+	if err != nil {
+		return nil, err
+	}
+	params, err := generator.GenerationParams(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached models.Roadmap
+	if err := db.Where("cache_key = ?", RoadmapCacheKey(job, prompt, params)).
+		Limit(1).Find(&cached).Error; err != nil {
+		return nil, err
+	}
+	if cached.ID == 0 {
+		return nil, nil
+	}
+	return &cached, nil
+}
+
+// SaveRoadmap stores a generated roadmap in the cache, replacing an older one with the same key
+func SaveRoadmap(db *gorm.DB, generator RoadmapGenerator, job *models.JobPosting, opts RoadmapOptions, response *RoadmapResponse) (*models.Roadmap, error) {
+	prompt, err := ResolvePrompt(opts)
+	if err != nil {
+		return nil, err
+	}
+	params, err := generator.GenerationParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	roadmap := models.Roadmap{
+		JobPostingID:  job.ID,
+		CacheKey:      RoadmapCacheKey(job, prompt, params),
+		JobHash:       RoadmapJobHash(job),
+		QuestionType:  prompt.QuestionType,
+		Lang:          prompt.Lang(),
+		Model:         params.Model,
+		Temperature:   params.Temperature,
+		MaxTokens:     params.MaxTokens,
+		TopP:          params.TopP,
+		TemplateID:    prompt.User.ID,
+		ProfileHash:   profileHash(prompt.Profile),
+		PromptVersion: prompt.User.Version,
+		Roadmap:       response.Roadmap,
+		Plan:          string(plan),
+	}
+	// 同じキーの古い結果は上書きする
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"roadmap", "plan", "temperature", "max_tokens", "top_p", "created_at"}),
+	}).Create(&roadmap).Error; err != nil {
+		return nil, err
+	}
+	return &roadmap, nil
+}
+
+// DecodeRoadmap returns the structured roadmap stored in the cache
+func DecodeRoadmap(roadmap *models.Roadmap) (*RoadmapResponse, error) {
+	response := RoadmapResponse{}
+	if roadmap.Plan != "" {
+		if err := json.Unmarshal([]byte(roadmap.Plan), &response); err != nil {
+			return nil, err
+		}
+	}
+	response.Roadmap = roadmap.Roadmap
+	return &response, nil
+}
+
+// InvalidateStaleRoadmaps deletes the cached roadmaps of a job that were generated from different job content.
+// job must have its Positions loaded.
+func InvalidateStaleRoadmaps(db *gorm.DB, job *models.JobPosting) error {
+	return db.Where("job_posting_id = ? AND job_hash <> ?", job.ID, RoadmapJobHash(job)).
+		Delete(&models.Roadmap{}).Error
+}
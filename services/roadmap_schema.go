@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// スキルの優先度
+const (
+	SkillPriorityHigh   = "high"
+	SkillPriorityMedium = "medium"
+	SkillPriorityLow    = "low"
+)
+
+// スキルの目標レベル
+var skillLevels = []string{"beginner", "intermediate", "advanced", "expert"}
+
+// 学習リソースの種類
+var resourceTypes = []string{"course", "book", "documentation", "tutorial", "project", "community", "certification", "other"}
+
+// 1フェーズの期間の上限（週）
+const maxPhaseWeeks = 104
+
+// RoadmapPhase is one step of the roadmap timeline
+type RoadmapPhase struct {
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	DurationWeeks int      `json:"duration_weeks"`
+	Goals         []string `json:"goals"`
+}
+
+// RoadmapSkill is a skill required for the job
+type RoadmapSkill struct {
+	Name        string `json:"name"`
+	Priority    string `json:"priority"`     // high, medium, low
+	TargetLevel string `json:"target_level"` // beginner, intermediate, advanced, expert
+	Reason      string `json:"reason"`
+}
+
+// RoadmapResource is a learning resource recommended in the roadmap
+type RoadmapResource struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	URL   string `json:"url,omitempty"`
+	Skill string `json:"skill,omitempty"`
+}
+
+// RoadmapSkillGap is the gap between a candidate's current level of a skill and the level the job requires
+type RoadmapSkillGap struct {
+	Skill         string `json:"skill"`
+	CurrentLevel  string `json:"current_level"`  // none, beginner, intermediate, advanced, expert
+	RequiredLevel string `json:"required_level"` // beginner, intermediate, advanced, expert
+	Weeks         int    `json:"weeks,omitempty"`
+	Advice        string `json:"advice,omitempty"`
+}
+
+// RoadmapValidationError lists the problems found in a roadmap returned by the model
+type RoadmapValidationError struct {
+	Problems []string
+}
+
+func (e *RoadmapValidationError) Error() string {
+	return "ロードマップの形式が不正です: " + strings.Join(e.Problems, "; ")
+}
+
+// parseRoadmapResponse decodes and validates the JSON returned by the model
+func parseRoadmapResponse(content string) (*RoadmapResponse, error) {
+	content = strings.TrimSpace(content)
+	// ローカルモデルはJSONモードでもコードブロックで囲むことがある
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+
+	var roadmap RoadmapResponse
+	if err := json.Unmarshal([]byte(content), &roadmap); err != nil {
+		return nil, &RoadmapValidationError{Problems: []string{"JSONとして解析できません: " + err.Error()}}
+	}
+	roadmap.Roadmap = ""
+
+	if err := roadmap.Validate(); err != nil {
+		return nil, err
+	}
+	return &roadmap, nil
+}
+
+// Validate checks the structured fields against the roadmap schema
+func (r *RoadmapResponse) Validate() error {
+	var problems []string
+	if strings.TrimSpace(r.Title) == "" {
+		problems = append(problems, "title が空です")
+	}
+
+	if len(r.Timeline) == 0 {
+		problems = append(problems, "timeline が空です")
+	}
+	for i, phase := range r.Timeline {
+		if strings.TrimSpace(phase.Title) == "" {
+			problems = append(problems, fmt.Sprintf("timeline[%d].title が空です", i))
+		}
+		if phase.DurationWeeks < 1 || phase.DurationWeeks > maxPhaseWeeks {
+			problems = append(problems, fmt.Sprintf("timeline[%d].duration_weeks は1以上%d以下にしてください: %d", i, maxPhaseWeeks, phase.DurationWeeks))
+		}
+	}
+
+	if len(r.Skills) == 0 {
+		problems = append(problems, "skills が空です")
+	}
+	for i, skill := range r.Skills {
+		if strings.TrimSpace(skill.Name) == "" {
+			problems = append(problems, fmt.Sprintf("skills[%d].name が空です", i))
+		}
+		if !containsString([]string{SkillPriorityHigh, SkillPriorityMedium, SkillPriorityLow}, skill.Priority) {
+			problems = append(problems, fmt.Sprintf("skills[%d].priority が不正です: %q", i, skill.Priority))
+		}
+		if !containsString(skillLevels, skill.TargetLevel) {
+			problems = append(problems, fmt.Sprintf("skills[%d].target_level が不正です: %q", i, skill.TargetLevel))
+		}
+	}
+
+	for i, resource := range r.Resources {
+		if strings.TrimSpace(resource.Title) == "" {
+			problems = append(problems, fmt.Sprintf("resources[%d].title が空です", i))
+		}
+		if !containsString(resourceTypes, resource.Type) {
+			problems = append(problems, fmt.Sprintf("resources[%d].type が不正です: %q", i, resource.Type))
+		}
+		if resource.URL != "" {
+			if u, err := url.Parse(resource.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("resources[%d].url が不正です: %q", i, resource.URL))
+			}
+		}
+	}
+
+	for i, gap := range r.SkillGaps {
+		if strings.TrimSpace(gap.Skill) == "" {
+			problems = append(problems, fmt.Sprintf("skill_gaps[%d].skill が空です", i))
+		}
+		if gap.CurrentLevel != "none" && !containsString(skillLevels, gap.CurrentLevel) {
+			problems = append(problems, fmt.Sprintf("skill_gaps[%d].current_level が不正です: %q", i, gap.CurrentLevel))
+		}
+		if !containsString(skillLevels, gap.RequiredLevel) {
+			problems = append(problems, fmt.Sprintf("skill_gaps[%d].required_level が不正です: %q", i, gap.RequiredLevel))
+		}
+		if gap.Weeks < 0 || gap.Weeks > maxPhaseWeeks {
+			problems = append(problems, fmt.Sprintf("skill_gaps[%d].weeks は0以上%d以下にしてください: %d", i, maxPhaseWeeks, gap.Weeks))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &RoadmapValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// TotalWeeks returns the sum of the phase durations
+func (r *RoadmapResponse) TotalWeeks() int {
+	total := 0
+	for _, phase := range r.Timeline {
+		total += phase.DurationWeeks
+	}
+	return total
+}
+
+// RenderMarkdown renders the structured roadmap as markdown in the default language
+func (r *RoadmapResponse) RenderMarkdown() string {
+	return renderDefaultMarkdown(r)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
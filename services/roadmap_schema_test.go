@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// TestRoadmapValidate はスキーマに合わないロードマップが検出されることをテストします
+func TestRoadmapValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		problem string
+	}{
+		{"JSONでない", "# ロードマップ", "JSONとして解析できません"},
+		{"タイムラインなし", `{"title": "t", "skills": [{"name": "Go", "priority": "high", "target_level": "expert"}]}`, "timeline が空です"},
+		{"期間が不正", `{"title": "t", "timeline": [{"title": "基礎", "duration_weeks": 0}], "skills": [{"name": "Go", "priority": "high", "target_level": "expert"}]}`, "timeline[0].duration_weeks"},
+		{"優先度が不正", `{"title": "t", "timeline": [{"title": "基礎", "duration_weeks": 2}], "skills": [{"name": "Go", "priority": "必須", "target_level": "expert"}]}`, "skills[0].priority"},
+		{"URLが不正", `{"title": "t", "timeline": [{"title": "基礎", "duration_weeks": 2}], "skills": [{"name": "Go", "priority": "low", "target_level": "expert"}], "resources": [{"title": "本", "type": "book", "url": "javascript:alert(1)"}]}`, "resources[0].url"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseRoadmapResponse(tc.content)
+			if assert.Error(t, err) {
+				assert.IsType(t, &RoadmapValidationError{}, err)
+				assert.Contains(t, err.Error(), tc.problem)
+			}
+		})
+	}
+
+	// コードブロックで囲まれていても解析できる
+	roadmap, err := parseRoadmapResponse("```json\n" + validRoadmapJSON + "\n```")
+	assert.NoError(t, err)
+	assert.Equal(t, "ロードマップ", roadmap.Title)
+}
+
+// TestRoadmapRepair は形式が不正な回答の修正を依頼することをテストします
+func TestRoadmapRepair(t *testing.T) {
+	job := &models.JobPosting{Title: "バックエンドエンジニア"}
+
+	// 1回目は不正、2回目で正しい形式を返す
+	provider := &recordingProvider{responses: []string{`{"title": "t", "timeline": []}`, validRoadmapJSON}}
+	roadmap, err := NewRoadmapService(provider).GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{QuestionType: "general"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go", roadmap.Skills[0].Name)
+	if assert.Len(t, provider.requests, 2) {
+		repair := provider.requests[1].Messages
+		assert.Len(t, repair, 4)
+		assert.Equal(t, "assistant", repair[2].Role)
+		assert.Contains(t, repair[3].Content, "timeline が空です")
+	}
+
+	// 修正されない場合は上限回数でエラーにする
+	provider = &recordingProvider{responses: []string{"not json"}}
+	_, err = NewRoadmapService(provider).GenerateCareerRoadmap(context.Background(), job, RoadmapOptions{QuestionType: "general"})
+	assert.Error(t, err)
+	assert.Len(t, provider.requests, 1+maxRoadmapRepairs)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"howtv-server/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ワーカープールの既定値
+const (
+	DefaultRoadmapWorkers     = 2
+	roadmapQueueSize          = 100
+	roadmapPollInterval       = 10 * time.Second
+	roadmapCallbackTimeout    = 30 * time.Second
+	roadmapCallbackRetries    = 3
+	roadmapCallbackTaskHeader = "X-Roadmap-Task-ID"
+)
+
+// RoadmapTaskCallback is the body posted to the callback URL when a task finishes
+type RoadmapTaskCallback struct {
+	Task    *models.RoadmapTask `json:"task"`
+	Roadmap *RoadmapResponse    `json:"roadmap,omitempty"`
+}
+
+// RoadmapWorkerPool generates roadmaps for the persisted RoadmapTasks with a fixed number of workers.
+// Pending tasks are picked up again after a restart.
+type RoadmapWorkerPool struct {
+	db           *gorm.DB
+	generator    RoadmapGenerator
+	workers      int
+	queue        chan uuid.UUID
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+func NewRoadmapWorkerPool(db *gorm.DB, generator RoadmapGenerator, workers int) *RoadmapWorkerPool {
+	if workers <= 0 {
+		workers = DefaultRoadmapWorkers
+	}
+	// コールバック先は接続時にも内部アドレスでないことを確認する
+	transport := newRetryTransport(roadmapCallbackRetries, 0)
+	transport.base = newCallbackTransport()
+	return &RoadmapWorkerPool{
+		db:        db,
+		generator: generator,
+		workers:   workers,
+		queue:     make(chan uuid.UUID, roadmapQueueSize),
+		client: &http.Client{
+			Timeout:   roadmapCallbackTimeout,
+			Transport: transport,
+		},
+		pollInterval: roadmapPollInterval,
+	}
+}
+
+// Start starts the workers. They stop when ctx is cancelled.
+func (p *RoadmapWorkerPool) Start(ctx context.Context) error {
+	// 前回の実行中に停止したタスクをやり直す
+	if err := p.db.Model(&models.RoadmapTask{}).Where("status = ?", models.RoadmapTaskRunning).
+		Update("status", models.RoadmapTaskPending).Error; err != nil {
+		return err
+	}
+
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+	go p.poll(ctx)
+	return nil
+}
+
+// Submit stores a new task for the job and queues it
+func (p *RoadmapWorkerPool) Submit(job *models.JobPosting, opts RoadmapOptions, refresh bool, callbackURL string) (*models.RoadmapTask, error) {
+	task := models.RoadmapTask{
+		JobPostingID: job.ID,
+		JobUUID:      job.UUID,
+		QuestionType: opts.QuestionType,
+		Lang:         opts.Lang,
+		TemplateID:   opts.TemplateID,
+		Seed:         opts.Seed,
+		Model:        opts.Model,
+		Refresh:      refresh,
+		CallbackURL:  callbackURL,
+	}
+	if err := p.db.Create(&task).Error; err != nil {
+		return nil, err
+	}
+	p.enqueue(task.ID)
+	return &task, nil
+}
+
+func (p *RoadmapWorkerPool) enqueue(id uuid.UUID) {
+	select {
+	case p.queue <- id:
+	default:
+		// キューが一杯の場合は次のポーリングで拾う
+	}
+}
+
+// poll queues the pending tasks, e.g. those stored before a restart or while the queue was full
+func (p *RoadmapWorkerPool) poll(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var ids []uuid.UUID
+		if err := p.db.Model(&models.RoadmapTask{}).Where("status = ?", models.RoadmapTaskPending).
+			Order("created_at").Limit(roadmapQueueSize).Pluck("id", &ids).Error; err != nil {
+			log.Printf("ロードマップ生成タスクの取得に失敗しました: %v", err)
+		}
+		for _, id := range ids {
+			p.enqueue(id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *RoadmapWorkerPool) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-p.queue:
+			p.process(ctx, id)
+		}
+	}
+}
+
+func (p *RoadmapWorkerPool) process(ctx context.Context, id uuid.UUID) {
+	// 同じタスクが複数回キューに入っても1つのワーカーだけが処理する
+	now := time.Now()
+	result := p.db.Model(&models.RoadmapTask{}).Where("id = ? AND status = ?", id, models.RoadmapTaskPending).
+		Updates(map[string]interface{}{"status": models.RoadmapTaskRunning, "started_at": now})
+	if result.Error != nil {
+		log.Printf("ロードマップ生成タスク %s の開始に失敗しました: %v", id, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	var task models.RoadmapTask
+	if err := p.db.First(&task, "id = ?", id).Error; err != nil {
+		log.Printf("ロードマップ生成タスク %s の取得に失敗しました: %v", id, err)
+		return
+	}
+
+	roadmap, err := p.generate(ctx, &task)
+	if err != nil && ctx.Err() != nil {
+		// 停止中に中断されたタスクは次回の起動時にやり直す
+		p.db.Model(&task).Update("status", models.RoadmapTaskPending)
+		return
+	}
+
+	finishedAt := time.Now()
+	task.FinishedAt = &finishedAt
+	if err != nil {
+		task.Status = models.RoadmapTaskFailed
+		task.Error = err.Error()
+		task.ErrorCode = RoadmapErrorCode(err)
+	} else if err := task.SetResult(roadmap); err != nil {
+		task.Status = models.RoadmapTaskFailed
+		task.Error = err.Error()
+		task.ErrorCode = ErrorCodeGenerationFailed
+	} else {
+		task.Status = models.RoadmapTaskSucceeded
+	}
+	if err := p.db.Save(&task).Error; err != nil {
+		log.Printf("ロードマップ生成タスク %s の保存に失敗しました: %v", id, err)
+		return
+	}
+
+	if task.CallbackURL != "" {
+		p.sendCallback(ctx, &task, roadmap)
+	}
+}
+
+func (p *RoadmapWorkerPool) generate(ctx context.Context, task *models.RoadmapTask) (*models.Roadmap, error) {
+	var job models.JobPosting
+	if err := p.db.Preload("Positions").First(&job, task.JobPostingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("求人情報が見つかりませんでした")
+		}
+		return nil, err
+	}
+	if err := models.LoadPositionParents(p.db, job.Positions); err != nil {
+		return nil, err
+	}
+
+	opts := RoadmapOptions{QuestionType: task.QuestionType, Lang: task.Lang, TemplateID: task.TemplateID, Seed: task.Seed, Model: task.Model}
+	roadmap, _, err := GetOrGenerateRoadmap(ctx, p.db, p.generator, &job, opts, task.Refresh)
+	return roadmap, err
+}
+
+// sendCallback posts the finished task to its callback URL and records the outcome
+func (p *RoadmapWorkerPool) sendCallback(ctx context.Context, task *models.RoadmapTask, roadmap *models.Roadmap) {
+	err := p.postCallback(ctx, task, roadmap)
+
+	updates := map[string]interface{}{"callback_status": models.CallbackDelivered, "callback_error": ""}
+	if err != nil {
+		log.Printf("ロードマップ生成タスク %s のコールバックに失敗しました: %v", task.ID, err)
+		updates = map[string]interface{}{"callback_status": models.CallbackFailed, "callback_error": err.Error()}
+	}
+	if err := p.db.Model(task).Updates(updates).Error; err != nil {
+		log.Printf("ロードマップ生成タスク %s の保存に失敗しました: %v", task.ID, err)
+	}
+}
+
+func (p *RoadmapWorkerPool) postCallback(ctx context.Context, task *models.RoadmapTask, roadmap *models.Roadmap) error {
+	payload := RoadmapTaskCallback{Task: task}
+	if roadmap != nil {
+		response, err := DecodeRoadmap(roadmap)
+		if err != nil {
+			return err
+		}
+		payload.Roadmap = response
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(roadmapCallbackTaskHeader, task.ID.String())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("コールバック先が %d を返しました", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"howtv-server/models"
+)
+
+// setupWorkerDB はワーカーから共有できるテスト用DBを作成します
+func setupWorkerDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("テスト用データベースの接続に失敗しました: %v", err)
+	}
+	// インメモリDBは接続ごとに別になるため1接続に制限する
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	db.AutoMigrate(&models.JobPosting{}, &models.Position{}, &models.Roadmap{}, &models.RoadmapTask{}, &models.LLMUsage{})
+	return db
+}
+
+// waitForTask はタスクが終了するまで待ちます
+func waitForTask(t *testing.T, db *gorm.DB, task *models.RoadmapTask) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		db.First(task, "id = ?", task.ID)
+		if task.Finished() && (task.CallbackURL == "" || task.CallbackStatus != "") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("タスクが終了しませんでした: %+v", task)
+}
+
+// TestRoadmapWorkerPool はタスクの処理とコールバックをテストします
+func TestRoadmapWorkerPool(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	callbacks := make(chan RoadmapTaskCallback, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload RoadmapTaskCallback
+		json.NewDecoder(r.Body).Decode(&payload)
+		assert.Equal(t, payload.Task.ID.String(), r.Header.Get("X-Roadmap-Task-ID"))
+		callbacks <- payload
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := NewRoadmapWorkerPool(db, NewRoadmapService(NewStubProvider()), 2)
+	// テストサーバーはループバックで待ち受けるため、アドレスを制限しないクライアントを使う
+	pool.client = server.Client()
+	assert.NoError(t, pool.Start(ctx))
+
+	task, err := pool.Submit(&job, RoadmapOptions{QuestionType: "general"}, false, server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoadmapTaskPending, task.Status)
+
+	waitForTask(t, db, task)
+	assert.Equal(t, models.RoadmapTaskSucceeded, task.Status)
+	assert.NotNil(t, task.RoadmapID)
+	assert.Equal(t, models.CallbackDelivered, task.CallbackStatus)
+
+	payload := <-callbacks
+	assert.Equal(t, models.RoadmapTaskSucceeded, payload.Task.Status)
+	if assert.NotNil(t, payload.Roadmap) {
+		assert.Equal(t, "Goエンジニアのロードマップ", payload.Roadmap.Title)
+	}
+}
+
+// TestRoadmapWorkerPoolRejectsInternalCallback は接続時に内部アドレスへのコールバックを拒否することをテストします
+func TestRoadmapWorkerPoolRejectsInternalCallback(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	called := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := NewRoadmapWorkerPool(db, NewRoadmapService(NewStubProvider()), 1)
+	assert.NoError(t, pool.Start(ctx))
+
+	// 受付時の検証をすり抜けた場合（DNSリバインディングなど）も送信しない
+	task, err := pool.Submit(&job, RoadmapOptions{QuestionType: "general"}, false, server.URL)
+	assert.NoError(t, err)
+
+	waitForTask(t, db, task)
+	assert.Equal(t, models.RoadmapTaskSucceeded, task.Status)
+	assert.Equal(t, models.CallbackFailed, task.CallbackStatus)
+	assert.Contains(t, task.CallbackError, ErrCallbackAddressNotAllowed.Error())
+	assert.Empty(t, called)
+}
+
+// TestValidateCallbackHost はコールバック先のアドレスの検証をテストします
+func TestValidateCallbackHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::", "::ffff:127.0.0.1"} {
+		assert.ErrorIs(t, ValidateCallbackHost(context.Background(), host), ErrCallbackAddressNotAllowed, host)
+	}
+	for _, host := range []string{"93.184.216.34", "2606:4700::1111"} {
+		assert.NoError(t, ValidateCallbackHost(context.Background(), host), host)
+	}
+}
+
+// TestRoadmapWorkerPoolResumesTasks は再起動前のタスクが処理されることをテストします
+func TestRoadmapWorkerPoolResumesTasks(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "データエンジニア"}
+	db.Create(&job)
+
+	// 前回の実行中に停止したタスクと、処理待ちのタスク
+	running := models.RoadmapTask{JobPostingID: job.ID, JobUUID: job.UUID, QuestionType: "general", Status: models.RoadmapTaskRunning}
+	pending := models.RoadmapTask{JobPostingID: job.ID, JobUUID: job.UUID, QuestionType: "skills"}
+	missing := models.RoadmapTask{JobPostingID: job.ID + 100, QuestionType: "general"}
+	db.Create(&running)
+	db.Create(&pending)
+	db.Create(&missing)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, NewRoadmapWorkerPool(db, NewRoadmapService(NewStubProvider()), 1).Start(ctx))
+
+	waitForTask(t, db, &running)
+	waitForTask(t, db, &pending)
+	waitForTask(t, db, &missing)
+	assert.Equal(t, models.RoadmapTaskSucceeded, running.Status)
+	assert.Equal(t, models.RoadmapTaskSucceeded, pending.Status)
+	assert.Equal(t, models.RoadmapTaskFailed, missing.Status)
+	assert.Equal(t, ErrorCodeGenerationFailed, missing.ErrorCode)
+}
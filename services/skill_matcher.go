@@ -0,0 +1,210 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"howtv-server/models"
+
+	"gorm.io/gorm"
+)
+
+// プロンプトに含めるスキルの最大数
+const maxPromptKeywords = 10
+
+// 求人の項目ごとの重み。職種名に出てくるスキルほど重要とみなす
+const (
+	skillWeightTitle        = 3
+	skillWeightRequirements = 2
+	skillWeightDescription  = 1
+)
+
+// SkillMatch is a skill of the taxonomy found in a text
+type SkillMatch struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Score    int    `json:"score"`
+}
+
+// SkillMatcher finds the skills of a taxonomy in texts by their names and aliases. Names of skills marked
+// AliasesOnly are not matched.
+// Latin terms only match whole words, so "go" does not match "good"; terms next to Japanese text match without spaces.
+type SkillMatcher struct {
+	skills []models.Skill
+	terms  []skillTerm
+}
+
+type skillTerm struct {
+	text  string
+	skill int // skills のインデックス
+}
+
+// skillOccurrence is a match of a term at [start, end) of a normalized text
+type skillOccurrence struct {
+	start, end int
+	skill      int
+}
+
+func NewSkillMatcher(skills []models.Skill) *SkillMatcher {
+	m := &SkillMatcher{skills: skills}
+	for i := range skills {
+		for _, term := range skills[i].MatchTerms() {
+			if term != "" {
+				m.terms = append(m.terms, skillTerm{text: term, skill: i})
+			}
+		}
+	}
+	return m
+}
+
+// Match returns the skills found in text ranked by the number of occurrences
+func (m *SkillMatcher) Match(text string) []SkillMatch {
+	return m.rank([]weightedText{{text: text, weight: 1}})
+}
+
+// MatchJob returns the skills found in the job ranked by score. A skill in the title counts more than one in
+// the requirements, which counts more than one in the description.
+func (m *SkillMatcher) MatchJob(job *models.JobPosting) []SkillMatch {
+	return m.rank([]weightedText{
+		{text: job.Title, weight: skillWeightTitle},
+		{text: job.Requirements, weight: skillWeightRequirements},
+		{text: job.Description, weight: skillWeightDescription},
+	})
+}
+
+type weightedText struct {
+	text   string
+	weight int
+}
+
+// rank scores the skills found in the texts. Ties are broken by the first occurrence, then by name.
+func (m *SkillMatcher) rank(texts []weightedText) []SkillMatch {
+	scores := map[int]int{}
+	firstSeen := map[int]int{}
+	seen := 0
+	for _, t := range texts {
+		for _, occ := range m.find(models.NormalizeSkillTerm(t.text)) {
+			if _, ok := scores[occ.skill]; !ok {
+				firstSeen[occ.skill] = seen
+				seen++
+			}
+			scores[occ.skill] += t.weight
+		}
+	}
+
+	matches := make([]SkillMatch, 0, len(scores))
+	order := make(map[string]int, len(scores))
+	for i, score := range scores {
+		skill := m.skills[i]
+		matches = append(matches, SkillMatch{Name: skill.Name, Category: skill.Category, Score: score})
+		order[skill.Name] = firstSeen[i]
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if order[matches[i].Name] != order[matches[j].Name] {
+			return order[matches[i].Name] < order[matches[j].Name]
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// find returns the non-overlapping occurrences in text, preferring the leftmost and then the longest term,
+// so that "react native" is not also counted as "react"
+func (m *SkillMatcher) find(text string) []skillOccurrence {
+	var all []skillOccurrence
+	for _, term := range m.terms {
+		for from := 0; from < len(text); {
+			i := strings.Index(text[from:], term.text)
+			if i < 0 {
+				break
+			}
+			start := from + i
+			end := start + len(term.text)
+			if atWordBoundary(text, start, end) {
+				all = append(all, skillOccurrence{start: start, end: end, skill: term.skill})
+			}
+			_, size := utf8.DecodeRuneInString(text[start:])
+			from = start + size
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].start != all[j].start {
+			return all[i].start < all[j].start
+		}
+		if all[i].end != all[j].end {
+			return all[i].end > all[j].end
+		}
+		return all[i].skill < all[j].skill
+	})
+	var occurrences []skillOccurrence
+	lastEnd := 0
+	for _, occ := range all {
+		if occ.start >= lastEnd {
+			occurrences = append(occurrences, occ)
+			lastEnd = occ.end
+		}
+	}
+	return occurrences
+}
+
+// atWordBoundary reports whether text[start:end] is not part of a longer Latin word.
+// Only ASCII letters and digits form words; Japanese text and symbols always separate terms.
+func atWordBoundary(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if isWordRune(first) && start > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(before) {
+			return false
+		}
+	}
+	if isWordRune(last) && end < len(text) {
+		if after, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(after) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_')
+}
+
+// skillMatcher はプロンプトの生成に使うスキル分類。APIで編集されると入れ替わる
+var skillMatcher atomic.Pointer[SkillMatcher]
+
+func init() {
+	skillMatcher.Store(NewSkillMatcher(models.DefaultSkills()))
+}
+
+// LoadSkillTaxonomy replaces the taxonomy used for prompts with the skills stored in db
+func LoadSkillTaxonomy(db *gorm.DB) error {
+	var skills []models.Skill
+	if err := db.Preload("Aliases").Order("id").Find(&skills).Error; err != nil {
+		return err
+	}
+	skillMatcher.Store(NewSkillMatcher(skills))
+	return nil
+}
+
+// MatchJobSkills returns the skills of the current taxonomy found in the job
+func MatchJobSkills(job *models.JobPosting) []SkillMatch {
+	return skillMatcher.Load().MatchJob(job)
+}
+
+// extractKeywords returns the names of the highest ranked skills of the job
+func extractKeywords(job *models.JobPosting) []string {
+	var keywords []string
+	for _, match := range MatchJobSkills(job) {
+		if len(keywords) == maxPromptKeywords {
+			break
+		}
+		keywords = append(keywords, match.Name)
+	}
+	return keywords
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+func matchNames(matches []SkillMatch) []string {
+	names := []string{}
+	for _, m := range matches {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// TestSkillMatcher は単語境界・日本語・順位付けをテストします
+func TestSkillMatcher(t *testing.T) {
+	matcher := NewSkillMatcher(models.DefaultSkills())
+
+	// 単語の一部には一致しない
+	assert.Empty(t, matcher.Match("A good engineer who can maintain our services"))
+	assert.Equal(t, []string{"Go"}, matchNames(matcher.Match("Experience with Golang is required")))
+
+	// 一般的な単語でもある名前は別名でのみ一致する
+	for _, text := range []string{
+		"Please express your ideas clearly",
+		"We go the extra mile",
+		"bootstrap projects",
+		"A node in the team",
+	} {
+		assert.Empty(t, matcher.Match(text), text)
+	}
+	assert.Equal(t, []string{"Express", "Node.js", "Bootstrap"}, matchNames(matcher.Match("Express.js on Node.js with Bootstrap CSS")))
+
+	// 日本語の別名と、日本語に隣接した英字の用語
+	assert.Equal(t, []string{"Machine Learning", "Go", "AWS"}, matchNames(matcher.Match("機械学習基盤をＧｏ言語とAWSで開発")))
+	assert.Equal(t, []string{"Go"}, matchNames(matcher.Match("Go言語での開発経験")))
+
+	// 長い用語を優先し、記号を含む用語も見つける
+	assert.Equal(t, []string{"React Native", "Node.js", "C++"}, matchNames(matcher.Match("React Native, node.js and C++")))
+
+	// 出現回数と項目の重みで順位を決め、結果は決定的
+	job := &models.JobPosting{
+		Title:        "Pythonエンジニア",
+		Requirements: "Docker, Python",
+		Description:  "Docker と Kubernetes を使ったインフラ。Kubernetes の運用",
+	}
+	matches := matcher.MatchJob(job)
+	assert.Equal(t, []string{"Python", "Docker", "Kubernetes"}, matchNames(matches))
+	assert.Equal(t, 5, matches[0].Score)
+	assert.Equal(t, models.SkillCategoryLanguage, matches[0].Category)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, matches, matcher.MatchJob(job))
+	}
+}
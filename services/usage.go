@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"howtv-server/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrLLMBudgetExceeded is returned instead of generating a roadmap once the monthly budget is used up
+var ErrLLMBudgetExceeded = errors.New("今月のLLM利用予算を超えました")
+
+// ModelPrice is the price of a model in USD per 1M tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// 既定のモデル価格（USD / 100万トークン）。モデル名の前方一致で最も長いものを使う
+var defaultModelPrices = map[string]ModelPrice{
+	"gpt-3.5-turbo": {Input: 0.5, Output: 1.5},
+	"gpt-4":         {Input: 30, Output: 60},
+	"gpt-4-turbo":   {Input: 10, Output: 30},
+	"gpt-4o":        {Input: 2.5, Output: 10},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.6},
+	"gpt-4.1":       {Input: 2, Output: 8},
+	"gpt-4.1-mini":  {Input: 0.4, Output: 1.6},
+}
+
+// UsageConfig configures a UsageTracker
+type UsageConfig struct {
+	// MonthlyBudget is the estimated cost in USD allowed per calendar month; 0 means no limit
+	MonthlyBudget float64
+	// Prices overrides or adds model prices. Models without a price cost 0, e.g. local models.
+	Prices map[string]ModelPrice
+}
+
+// UsageTracker records the usage of LLM calls and enforces the monthly budget
+type UsageTracker struct {
+	db     *gorm.DB
+	budget float64
+	prices map[string]ModelPrice
+	now    func() time.Time
+}
+
+func NewUsageTracker(db *gorm.DB, cfg UsageConfig) *UsageTracker {
+	prices := make(map[string]ModelPrice, len(defaultModelPrices)+len(cfg.Prices))
+	for model, price := range defaultModelPrices {
+		prices[model] = price
+	}
+	for model, price := range cfg.Prices {
+		prices[model] = price
+	}
+	return &UsageTracker{db: db, budget: cfg.MonthlyBudget, prices: prices, now: time.Now}
+}
+
+// EstimateCost returns the estimated cost in USD of a call to model
+func (t *UsageTracker) EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	price, matched := ModelPrice{}, ""
+	for prefix, p := range t.prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			price, matched = p, prefix
+		}
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000
+}
+
+// Record stores the usage of a call with its estimated cost
+func (t *UsageTracker) Record(usage *models.LLMUsage) error {
+	usage.Cost = t.EstimateCost(usage.Model, usage.PromptTokens, usage.CompletionTokens)
+	return t.db.Create(usage).Error
+}
+
+// MonthlyBudget returns the budget per month in USD, or 0 without a limit
+func (t *UsageTracker) MonthlyBudget() float64 {
+	return t.budget
+}
+
+// MonthlyCost returns the estimated cost of the calls made in the current calendar month
+func (t *UsageTracker) MonthlyCost() (float64, error) {
+	now := t.now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var cost float64
+	err := t.db.Model(&models.LLMUsage{}).Where("created_at >= ?", monthStart).
+		Select("COALESCE(SUM(cost), 0)").Scan(&cost).Error
+	return cost, err
+}
+
+// CheckBudget returns ErrLLMBudgetExceeded when the cost of this month has reached the budget
+func (t *UsageTracker) CheckBudget() error {
+	if t.budget <= 0 {
+		return nil
+	}
+	cost, err := t.MonthlyCost()
+	if err != nil {
+		return err
+	}
+	if cost >= t.budget {
+		return ErrLLMBudgetExceeded
+	}
+	return nil
+}
+
+// UsageAggregate is the usage of the calls sharing a key
+type UsageAggregate struct {
+	Key              string  `gorm:"column:usage_key" json:"key"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// JobUsageAggregate is the usage of the calls made for one job posting
+type JobUsageAggregate struct {
+	UsageAggregate
+	JobPostingID uint   `json:"job_posting_id"`
+	JobUUID      string `json:"job_uuid"`
+	JobTitle     string `json:"job_title"`
+}
+
+// UsageSummary aggregates the calls made in [From, To)
+type UsageSummary struct {
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"`
+	Total          UsageAggregate      `json:"total"`
+	ByDay          []UsageAggregate    `json:"by_day"` // 日付はUTC
+	ByJob          []JobUsageAggregate `json:"by_job"`
+	ByQuestionType []UsageAggregate    `json:"by_question_type"`
+}
+
+// 集計に共通の列
+const usageAggregateColumns = "COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(cost), 0) AS cost, " +
+	"COALESCE(AVG(latency_ms), 0) AS avg_latency_ms"
+
+// SummarizeUsage aggregates the calls made in [from, to) in total, by day, by job and by question type
+func SummarizeUsage(db *gorm.DB, from, to time.Time) (*UsageSummary, error) {
+	summary := &UsageSummary{
+		From:           from,
+		To:             to,
+		ByDay:          []UsageAggregate{},
+		ByJob:          []JobUsageAggregate{},
+		ByQuestionType: []UsageAggregate{},
+	}
+	inRange := func() *gorm.DB {
+		return db.Model(&models.LLMUsage{}).Where("llm_usage.created_at >= ? AND llm_usage.created_at < ?", from, to)
+	}
+
+	if err := inRange().Select(usageAggregateColumns).Scan(&summary.Total).Error; err != nil {
+		return nil, err
+	}
+	day := usageDayExpr(db)
+	if err := inRange().Select(day + " AS usage_key, " + usageAggregateColumns).
+		Group(day).Order("usage_key").Scan(&summary.ByDay).Error; err != nil {
+		return nil, err
+	}
+	if err := inRange().Select("question_type AS usage_key, " + usageAggregateColumns).
+		Group("question_type").Order("cost DESC").Scan(&summary.ByQuestionType).Error; err != nil {
+		return nil, err
+	}
+	if err := inRange().
+		Select("llm_usage.job_posting_id, job_postings.uuid AS job_uuid, job_postings.title AS job_title, " + usageAggregateColumns).
+		Joins("LEFT JOIN job_postings ON job_postings.id = llm_usage.job_posting_id").
+		Group("llm_usage.job_posting_id, job_postings.uuid, job_postings.title").
+		Order("cost DESC").Scan(&summary.ByJob).Error; err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// usageDayExpr formats the day of a call as YYYY-MM-DD. DATE() returns a date value on PostgreSQL and MySQL,
+// which would be scanned as a timestamp.
+func usageDayExpr(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "TO_CHAR(llm_usage.created_at, 'YYYY-MM-DD')"
+	case "mysql":
+		return "DATE_FORMAT(llm_usage.created_at, '%Y-%m-%d')"
+	}
+	return "DATE(llm_usage.created_at)"
+}
+
+// tokenUsage collects the token counts reported by a provider during one call
+type tokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type tokenUsageKey struct{}
+
+// withTokenUsage returns a context in which providers report the token counts of the call
+func withTokenUsage(ctx context.Context) (context.Context, *tokenUsage) {
+	usage := &tokenUsage{}
+	return context.WithValue(ctx, tokenUsageKey{}, usage), usage
+}
+
+// reportTokenUsage is called by providers that know the token counts of a completion
+func reportTokenUsage(ctx context.Context, promptTokens, completionTokens int) {
+	if usage, ok := ctx.Value(tokenUsageKey{}).(*tokenUsage); ok {
+		usage.PromptTokens += promptTokens
+		usage.CompletionTokens += completionTokens
+	}
+}
+
+// recordUsage stores the usage of a call. A failure to record does not fail the generation.
+func (s *RoadmapService) recordUsage(job *models.JobPosting, prompt *ResolvedPrompt, req ChatRequest, tokens *tokenUsage, latency time.Duration, streamed, failed bool) {
+	if s.usage == nil {
+		return
+	}
+	err := s.usage.Record(&models.LLMUsage{
+		JobPostingID:     job.ID,
+		QuestionType:     prompt.QuestionType,
+		Provider:         s.provider.Name(),
+		Model:            req.Model,
+		PromptTokens:     tokens.PromptTokens,
+		CompletionTokens: tokens.CompletionTokens,
+		LatencyMs:        latency.Milliseconds(),
+		Streamed:         streamed,
+		Failed:           failed,
+	})
+	if err != nil {
+		log.Printf("警告: LLMの利用量を記録できませんでした: %v", err)
+	}
+}
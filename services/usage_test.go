@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"howtv-server/models"
+)
+
+// TestUsageTracker は利用量の記録・集計と月間予算をテストします
+func TestUsageTracker(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	// 1回あたり (1000*2 + 500*8) / 100万 = 0.006 USD
+	tracker := NewUsageTracker(db, UsageConfig{
+		MonthlyBudget: 0.01,
+		Prices:        map[string]ModelPrice{"recording-model": {Input: 2, Output: 8}},
+	})
+	assert.InDelta(t, 0.15*1000/1_000_000, tracker.EstimateCost("gpt-4o-mini-2024-07-18", 1000, 0), 1e-12)
+	assert.Equal(t, 0.0, tracker.EstimateCost("llama3", 1000, 1000))
+
+	service := NewRoadmapService(&recordingProvider{}).TrackUsage(tracker)
+	_, cached, err := GetOrGenerateRoadmap(context.Background(), db, service, &job, RoadmapOptions{QuestionType: "skills"}, false)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	_, err = service.StreamCareerRoadmap(context.Background(), &job, RoadmapOptions{}, func(string) error { return nil })
+	assert.NoError(t, err)
+
+	var usages []models.LLMUsage
+	db.Order("id").Find(&usages)
+	if assert.Len(t, usages, 2) {
+		assert.Equal(t, job.ID, usages[0].JobPostingID)
+		assert.Equal(t, "skills", usages[0].QuestionType)
+		assert.Equal(t, "recording-model", usages[0].Model)
+		assert.Equal(t, 1000, usages[0].PromptTokens)
+		assert.InDelta(t, 0.006, usages[0].Cost, 1e-9)
+		assert.True(t, usages[1].Streamed)
+	}
+
+	now := time.Now()
+	summary, err := SummarizeUsage(db, now.Add(-time.Hour), now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), summary.Total.Calls)
+	assert.Equal(t, int64(1000), summary.Total.CompletionTokens)
+	assert.InDelta(t, 0.012, summary.Total.Cost, 1e-9)
+	assert.NotEmpty(t, summary.ByDay)
+	assert.Len(t, summary.ByQuestionType, 2)
+	if assert.Len(t, summary.ByJob, 1) {
+		assert.Equal(t, "Goエンジニア", summary.ByJob[0].JobTitle)
+		assert.Equal(t, int64(2), summary.ByJob[0].Calls)
+	}
+
+	// 予算を超えると新しい生成は拒否し、キャッシュ済みのロードマップだけを返す
+	_, err = service.GenerateCareerRoadmap(context.Background(), &job, RoadmapOptions{QuestionType: "career"})
+	assert.True(t, errors.Is(err, ErrLLMBudgetExceeded))
+	assert.Equal(t, ErrorCodeBudgetExceeded, RoadmapErrorCode(err))
+	_, cached, err = GetOrGenerateRoadmap(context.Background(), db, service, &job, RoadmapOptions{QuestionType: "skills"}, true)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+
+	// 予算の管理は月ごと
+	tracker.now = func() time.Time { return now.AddDate(0, 1, 0) }
+	assert.NoError(t, tracker.CheckBudget())
+}
+
+// failingStreamProvider は途中まで出力してから失敗するストリームを返すテスト用のプロバイダーです
+type failingStreamProvider struct {
+	recordingProvider
+}
+
+func (p *failingStreamProvider) Stream(ctx context.Context, req ChatRequest, onToken func(string) error) (string, error) {
+	if err := onToken("途中までの出力です"); err != nil {
+		return "", err
+	}
+	return "", ErrLLMUnavailable
+}
+
+// TestStreamUsageOnFailure は失敗・中断したストリームの利用量も記録されることをテストします
+func TestStreamUsageOnFailure(t *testing.T) {
+	db := setupWorkerDB(t)
+	job := models.JobPosting{Title: "Goエンジニア"}
+	db.Create(&job)
+
+	tracker := NewUsageTracker(db, UsageConfig{Prices: map[string]ModelPrice{"recording-model": {Input: 2, Output: 8}}})
+	service := NewRoadmapService(&failingStreamProvider{}).TrackUsage(tracker)
+
+	// プロバイダーのエラー
+	_, err := service.StreamCareerRoadmap(context.Background(), &job, RoadmapOptions{}, func(string) error { return nil })
+	assert.True(t, errors.Is(err, ErrLLMUnavailable))
+	// クライアントの切断
+	_, err = service.StreamCareerRoadmap(context.Background(), &job, RoadmapOptions{}, func(string) error { return context.Canceled })
+	assert.Error(t, err)
+
+	var usages []models.LLMUsage
+	db.Order("id").Find(&usages)
+	if assert.Len(t, usages, 2) {
+		for _, usage := range usages {
+			assert.True(t, usage.Failed)
+			assert.True(t, usage.Streamed)
+			assert.Positive(t, usage.PromptTokens)
+			assert.Equal(t, estimateTokens(9), usage.CompletionTokens)
+			assert.Positive(t, usage.Cost)
+		}
+	}
+
+	// 成功したストリームは失敗として記録しない
+	service = NewRoadmapService(&recordingProvider{}).TrackUsage(tracker)
+	_, err = service.StreamCareerRoadmap(context.Background(), &job, RoadmapOptions{}, func(string) error { return nil })
+	assert.NoError(t, err)
+	var last models.LLMUsage
+	db.Last(&last)
+	assert.False(t, last.Failed)
+	assert.Equal(t, 500, last.CompletionTokens)
+}
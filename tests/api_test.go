@@ -11,12 +11,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"howtv-server/config"
 	"howtv-server/controllers"
+	"howtv-server/database"
 	"howtv-server/models"
+	"howtv-server/services"
 )
 
 var testRouter *gin.Engine
@@ -63,19 +64,19 @@ func setup() {
 	// GinをTestモードに設定
 	gin.SetMode(gin.TestMode)
 
-	// テスト用のDBを作成
+	// テスト用のDBを作成してマイグレーションする
 	var err error
-	testDB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	testDB, err = openTestDB()
 	if err != nil {
 		panic("テスト用データベースの接続に失敗しました: " + err.Error())
 	}
 
-	// マイグレーション
-	testDB.AutoMigrate(&models.Company{}, &models.JobPosting{}, &models.Position{})
-
 	// コントローラーにDBをセット
 	controllers.DB = testDB
 
+	// ネットワークを使わないスタブでロードマップを生成する
+	controllers.Roadmaps = services.NewRoadmapService(services.NewStubProvider())
+
 	// 設定を読み込む
 	config.LoadConfig()
 
@@ -86,6 +87,39 @@ func setup() {
 	testRouter = setupTestRouter()
 }
 
+// openTestDB はテスト用のDBに接続します。
+// 既定はインメモリのSQLiteで、TEST_DB_DRIVER と TEST_DB_DSN で PostgreSQL・MySQL に切り替えられます
+// (例: docker compose up -d postgres の後に TEST_DB_DRIVER=postgres TEST_DB_DSN="host=localhost user=howtv password=howtv dbname=howtv sslmode=disable" go test ./tests)。
+// SQLite以外では前回のテストのテーブルを削除してから作り直します。
+func openTestDB() (*gorm.DB, error) {
+	driver := os.Getenv("TEST_DB_DRIVER")
+	dsn := os.Getenv("TEST_DB_DSN")
+	if driver == "" || driver == "sqlite" {
+		driver = "sqlite"
+		if dsn == "" {
+			dsn = ":memory:"
+		}
+	}
+
+	dialector, err := database.Dialector(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if driver != "sqlite" {
+		if err := db.Migrator().DropTable("job_positions", "job_skills",
+			&models.JobStatusChange{}, &models.RoadmapTask{}, &models.Roadmap{}, &models.LLMUsage{},
+			&models.JobPosting{}, &models.Company{}, &models.PositionAlias{}, &models.Position{},
+			&models.SkillAlias{}, &models.Skill{}, &models.SeedFile{}); err != nil {
+			return nil, err
+		}
+	}
+	return db, models.Migrate(db)
+}
+
 // teardown はテスト後のクリーンアップを行います
 func teardown() {
 	// 必要に応じてリソースを解放
@@ -105,13 +139,50 @@ func setupTestRouter() *gin.Engine {
 		v1.PUT("/jobs/:uuid", controllers.UpdateJobPosting)
 		v1.DELETE("/jobs/:uuid", controllers.DeleteJobPosting)
 
+		// Job status lifecycle
+		v1.GET("/job-statuses", controllers.GetJobStatuses)
+		v1.POST("/jobs/:uuid/publish", controllers.PublishJobPosting)
+		v1.POST("/jobs/:uuid/pause", controllers.PauseJobPosting)
+		v1.POST("/jobs/:uuid/close", controllers.CloseJobPosting)
+		v1.GET("/jobs/:uuid/status-history", controllers.GetJobStatusHistory)
+
+		// Companies
+		v1.GET("/companies", controllers.GetCompanies)
+		v1.GET("/companies/:id", controllers.GetCompany)
+		v1.POST("/companies", controllers.CreateCompany)
+		v1.PUT("/companies/:id", controllers.UpdateCompany)
+		v1.DELETE("/companies/:id", controllers.DeleteCompany)
+		v1.GET("/companies/:id/jobs", controllers.GetCompanyJobs)
+
 		// Positions
 		v1.GET("/positions", controllers.GetPositions)
 		v1.POST("/positions", controllers.CreatePosition)
+		v1.GET("/positions/tree", controllers.GetPositionTree)
+		v1.GET("/positions/:id", controllers.GetPosition)
+		v1.PUT("/positions/:id", controllers.UpdatePosition)
+		v1.DELETE("/positions/:id", controllers.DeletePosition)
+		v1.GET("/positions/:id/jobs", controllers.GetPositionJobs)
 		v1.POST("/jobs/:uuid/positions", controllers.AssignPositionsToJob)
 
-		// Roadmap Generation (モックモードでのみテスト)
-		// v1.GET("/jobs/:uuid/roadmap", controllers.GenerateRoadmap)
+		// Skill taxonomy
+		v1.GET("/skills", controllers.GetSkills)
+		v1.GET("/skills/match", controllers.MatchSkills)
+		v1.GET("/skills/:id", controllers.GetSkill)
+		v1.POST("/skills", controllers.CreateSkill)
+		v1.PUT("/skills/:id", controllers.UpdateSkill)
+		v1.DELETE("/skills/:id", controllers.DeleteSkill)
+		v1.GET("/skills/:id/jobs", controllers.GetSkillJobs)
+		v1.PUT("/jobs/:uuid/skills", controllers.SetJobSkills)
+
+		// Roadmap Generation (スタブプロバイダーでテスト)
+		v1.GET("/jobs/:uuid/roadmap", controllers.GenerateRoadmap)
+		v1.POST("/jobs/:uuid/roadmap", controllers.GeneratePersonalizedRoadmap)
+		v1.GET("/jobs/:uuid/roadmap/stream", controllers.GenerateRoadmapStream)
+		v1.POST("/jobs/:uuid/roadmaps", controllers.CreateRoadmapTask)
+		v1.GET("/roadmap-tasks/:id", controllers.GetRoadmapTask)
+
+		// Admin
+		v1.GET("/admin/llm-usage", controllers.GetLLMUsage)
 	}
 
 	return r
@@ -141,7 +212,7 @@ func seedTestData() {
 	// 求人を作成
 	job := models.JobPosting{
 		UUID:           uuid.New(),
-		CompanyID:      company.UUID,
+		CompanyID:      company.ID,
 		Title:          "テストエンジニア",
 		Description:    "テスト説明文",
 		Requirements:   "テスト要件",
@@ -172,13 +243,17 @@ func TestGetAllJobs(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// レスポンスをパース
-	var response []models.JobPosting
+	var response struct {
+		Data  []models.JobPosting `json:"data"`
+		Total int64               `json:"total"`
+	}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 
 	// 少なくとも1件の求人が返されていることを確認
-	assert.True(t, len(response) > 0)
-	assert.Equal(t, "テストエンジニア", response[0].Title)
+	assert.True(t, len(response.Data) > 0)
+	assert.True(t, response.Total > 0)
+	assert.Equal(t, "テストエンジニア", response.Data[0].Title)
 }
 
 // TestGetSingleJob は特定の求人取得APIをテストします
@@ -263,3 +338,43 @@ func TestNonExistentJob(t *testing.T) {
 	// コントローラーから返される実際のメッセージと一致させる
 	assert.Contains(t, w.Body.String(), "Job posting not found")
 }
+
+// TestGenerateRoadmapWithStub はスタブプロバイダーでロードマップ生成APIをテストします
+func TestGenerateRoadmapWithStub(t *testing.T) {
+	var job models.JobPosting
+	testDB.First(&job)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/jobs/"+job.UUID.String()+"/roadmap?question_type=skills", nil)
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		JobTitle     string                  `json:"job_title"`
+		Roadmap      string                  `json:"roadmap"`
+		QuestionType string                  `json:"question_type"`
+		Skills       []services.RoadmapSkill `json:"skills"`
+		Timeline     []services.RoadmapPhase `json:"timeline"`
+		TotalWeeks   int                     `json:"total_weeks"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	// スタブは同じ求人に対して常に同じロードマップを返す
+	assert.Equal(t, "テストエンジニア", response.JobTitle)
+	assert.Equal(t, "skills", response.QuestionType)
+	assert.Contains(t, response.Roadmap, "# テストエンジニアのロードマップ")
+
+	// 構造化されたスキルとタイムラインが返される
+	assert.NotEmpty(t, response.Skills)
+	assert.Len(t, response.Timeline, 3)
+	assert.Equal(t, 20, response.TotalWeeks)
+
+	// markdown=false ではマークダウンを省略する
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/jobs/"+job.UUID.String()+"/roadmap?question_type=skills&markdown=false", nil)
+	testRouter.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "\"roadmap\"")
+}